@@ -0,0 +1,88 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Github rejects JWTs which are valid for more than 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+
+	// Refresh installation tokens a while before they actually expire, so that
+	// a token handed out to git does not expire in the middle of a clone.
+	installationTokenExpiryMargin = 5 * time.Minute
+)
+
+// NewTokenSource returns a token source for the authentication mode configured
+// in the source: either a static access token, or an installation token minted
+// on behalf of a Github App.
+func NewTokenSource(ctx context.Context, s *Source) (oauth2.TokenSource, error) {
+	if s.AccessToken != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: s.AccessToken}), nil
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(s.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %s", err)
+	}
+	source := &appTokenSource{
+		ctx:            ctx,
+		endpoint:       s.V3Endpoint,
+		appID:          s.AppID,
+		installationID: s.InstallationID,
+		sign: func(claims jwt.Claims) (string, error) {
+			return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		},
+	}
+	return oauth2.ReuseTokenSource(nil, source), nil
+}
+
+// appTokenSource exchanges a JWT signed with the private key of a Github App
+// for an installation access token.
+type appTokenSource struct {
+	ctx            context.Context
+	endpoint       string
+	appID          int64
+	installationID int64
+	sign           func(jwt.Claims) (string, error)
+}
+
+// Token implements oauth2.TokenSource.
+func (a *appTokenSource) Token() (*oauth2.Token, error) {
+	// Backdate the token to allow for some clock drift between us and Github.
+	now := time.Now()
+	signed, err := a.sign(jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(appJWTLifetime)),
+		Issuer:    strconv.FormatInt(a.appID, 10),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign jwt: %s", err)
+	}
+
+	client := oauth2.NewClient(a.ctx, oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: signed},
+	))
+	v3, err := newV3Client(a.endpoint, client)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := v3.Apps.CreateInstallationToken(a.ctx, a.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token: %s", err)
+	}
+	if token.GetToken() == "" {
+		return nil, errors.New("received an empty installation token")
+	}
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Add(-installationTokenExpiryMargin),
+	}, nil
+}
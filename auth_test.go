@@ -0,0 +1,124 @@
+package resource_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestNewTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/app/installations/42/access_tokens", r.URL.Path)
+
+		claims := &jwt.RegisteredClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims, func(*jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "7", claims.Issuer)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      "installation-token",
+			"expires_at": time.Now().Add(time.Hour),
+		})
+	}))
+	defer server.Close()
+
+	source := &resource.Source{
+		Repository:     "itsdalmo/test-repository",
+		AppID:          7,
+		InstallationID: 42,
+		PrivateKey:     string(privateKey),
+		V3Endpoint:     server.URL,
+		V4Endpoint:     server.URL + "/graphql",
+	}
+	require.NoError(t, source.Validate())
+
+	tokenSource, err := resource.NewTokenSource(context.TODO(), source)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		token, err := tokenSource.Token()
+		if assert.NoError(t, err) {
+			assert.Equal(t, "installation-token", token.AccessToken)
+		}
+	}
+	assert.Equal(t, 1, requests, "expected the installation token to be reused")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, tokenSource, err = resource.NewManager(ctx, source)
+	require.NoError(t, err)
+
+	_, err = tokenSource.Token()
+	assert.Error(t, err, "expected minting to use the callers context")
+	assert.Equal(t, 1, requests)
+}
+
+func TestSourceValidateAuthentication(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		wantErr     bool
+	}{
+		{
+			description: "access token is valid",
+			source:      resource.Source{Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken"},
+		},
+		{
+			description: "github app is valid",
+			source:      resource.Source{Repository: "itsdalmo/test-repository", AppID: 1, InstallationID: 2, PrivateKey: "key"},
+		},
+		{
+			description: "requires some form of authentication",
+			source:      resource.Source{Repository: "itsdalmo/test-repository"},
+			wantErr:     true,
+		},
+		{
+			description: "does not allow both access token and github app",
+			source:      resource.Source{Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken", AppID: 1, InstallationID: 2, PrivateKey: "key"},
+			wantErr:     true,
+		},
+		{
+			description: "requires all github app credentials",
+			source:      resource.Source{Repository: "itsdalmo/test-repository", AppID: 1, PrivateKey: "key"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.source.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,18 +1,37 @@
 package resource
 
 import (
+	"context"
 	"fmt"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/shurcooL/githubv4"
 )
 
 // Check (business logic)
-func Check(request CheckRequest, manager Github) (CheckResponse, error) {
+func Check(ctx context.Context, request CheckRequest, manager Github) (CheckResponse, error) {
+	response, decisions, err := Evaluate(ctx, request, manager)
+	if err != nil {
+		return nil, err
+	}
+	logger := LoggerFromContext(ctx)
+	for _, d := range decisions {
+		logger.Explain(d)
+	}
+	return response, nil
+}
+
+// Evaluate lists the pull requests for a check request and returns the new
+// versions, along with the decision made for each pull request.
+func Evaluate(ctx context.Context, request CheckRequest, manager Github) (CheckResponse, []Decision, error) {
 	var response CheckResponse
+	start := time.Now()
 
 	// Filter out pull request if it does not have a filtered state
 	filterStates := []githubv4.PullRequestState{githubv4.PullRequestStateOpen}
@@ -20,131 +39,712 @@ func Check(request CheckRequest, manager Github) (CheckResponse, error) {
 		filterStates = request.Source.States
 	}
 
-	pulls, err := manager.ListPullRequests(filterStates)
+	// Only fetch files if paths/ignore_paths are specified.
+	filterPaths := len(request.Source.Paths) > 0 || len(request.Source.IgnorePaths) > 0
+
+	// Pull requests which were conflicting in the current version are not updated when the
+	// base branch resolves their conflicts.
+	conflicting := make(map[string]bool)
+	if request.Source.IgnoreConflicting && request.Version.Conflicting != "" {
+		for _, pr := range strings.Split(request.Version.Conflicting, ",") {
+			conflicting[pr] = true
+		}
+	}
+
+	// Pull requests are not updated when their base branch changes, or when their
+	// statuses change, so all of them have to be listed.
+	since := request.Version.CommittedDate
+	if request.Source.TriggerOnBaseChange || len(conflicting) > 0 || len(request.Source.RequiredStatuses) > 0 || len(request.Source.RequiredCheckRuns) > 0 {
+		since = time.Time{}
+	}
+
+	pulls, err := manager.ListPullRequests(ctx, filterStates, filterPaths, since)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get last commits: %s", err)
+		return nil, nil, fmt.Errorf("failed to get last commits: %s", err)
 	}
 
-	disableSkipCI := request.Source.DisableCISkip
+	// The base branch of the current version, so that a change of its head is detected even
+	// when the new head was committed before the current version (e.g. a fast-forward).
+	var versionBase string
+	if request.Source.TriggerOnBaseChange && request.Version.BaseCommit != "" {
+		for _, p := range pulls {
+			if strconv.Itoa(p.Number) == request.Version.PR {
+				versionBase = p.BaseRefName
+			}
+		}
+	}
+
+	// Permissions are cached for the duration of the check.
+	permissions := make(map[string]bool)
+
+	// The regular expressions are compiled once for all pull requests.
+	regexps, err := request.Source.regexps()
+	if err != nil {
+		return nil, nil, err
+	}
 
-Loop:
+	decisions := make([]Decision, 0, len(pulls))
+	var stillConflicting []string
 	for _, p := range pulls {
-		// [ci skip]/[skip ci] in Pull request title
-		if !disableSkipCI && ContainsSkipCI(p.Title) {
-			continue
+		trigger, err := findTrigger(ctx, request, manager, p, permissions, regexps["comment_trigger"])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check triggers: %s", err)
 		}
 
-		// [ci skip]/[skip ci] in Commit message
-		if !disableSkipCI && ContainsSkipCI(p.Tip.Message) {
-			continue
+		// The approval dates the version, since a fork can be approved long after it was pushed.
+		var approval *TimelineItem
+		if requiresOkToTest(request.Source, p) {
+			approval, err = findOkToTest(ctx, request, manager, p, permissions)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to check ok-to-test: %s", err)
+			}
 		}
 
-		// Filter pull request if the BaseBranch does not match the one specified in source
-		if request.Source.BaseBranch != "" && p.PullRequestObject.BaseRefName != request.Source.BaseBranch {
-			continue
+		// Versions from before push dates were recorded are compared by the commit date
+		// they were created with, so that upgrading does not rebuild them.
+		date := versionDate(request.Source, p, true, trigger, approval)
+		compare := versionDate(request.Source, p, request.Version.PushedDate != nil || request.Version.PR == "", trigger, approval)
+
+		base := baseDate(request, p, versionBase, conflicting, start)
+		if base.After(date) {
+			date = base
+		}
+		if base.After(compare) {
+			compare = base
 		}
 
-		// Filter out commits that are too old.
-		if !p.UpdatedDate().Time.After(request.Version.CommittedDate) {
-			continue
+		d, err := evaluatePullRequest(request, p, compare, regexps)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		// Filter out pull request if it does not contain at least one of the desired labels
-		if len(request.Source.Labels) > 0 {
-			labelFound := false
+		// Only look up the commits if the tip is skipped, since a commit which is not skipped triggers a build.
+		var pushed []CommitObject
+		if _, ok := matchSkipCI(request.Source.skipCIPatterns(), p.Tip.Message); d.Accepted && ok && !request.Source.DisableCISkip && request.Source.SkipCIAllCommits {
+			pushed, err = pushedCommits(ctx, request, manager, p)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to list commits: %s", err)
+			}
+			if skipped := skippedCommits(request.Source, pushed); skipped > 0 {
+				d.Accepted = false
+				d.Filter = "disable_ci_skip"
+				d.Reason = fmt.Sprintf("all %d commit(s) since the current version are marked skip", skipped)
+			}
+		}
 
-		LabelLoop:
-			for _, wantedLabel := range request.Source.Labels {
-				for _, targetLabel := range p.Labels {
-					if targetLabel.Name == wantedLabel {
-						labelFound = true
-						break LabelLoop
-					}
+		if d.Accepted && requiresOkToTest(request.Source, p) && approval == nil {
+			d.Accepted = false
+			d.Filter = "ok_to_test"
+			d.Reason = "not marked ok-to-test by a user with write permission since the last push"
+		}
+		decisions = append(decisions, d)
+
+		// Pull requests which are (still) conflicting are recorded in the new versions, so that
+		// they are not overtaken by them once the base branch resolves their conflicts.
+		if d.Filter == "ignore_conflicting" || (conflicting[strconv.Itoa(p.Number)] && p.State == githubv4.PullRequestStateOpen && p.Mergeable != githubv4.MergeableStateMergeable) {
+			stillConflicting = append(stillConflicting, strconv.Itoa(p.Number))
+		}
+		if !d.Accepted {
+			continue
+		}
+
+		// Every commit pushed since the current version gets a version, but there is
+		// nothing to build before the first version (which is just the latest commit).
+		// Only the tip is approved (ok-to-test) or has its statuses checked, so gated
+		// pull requests only emit the tip.
+		gated := requiresOkToTest(request.Source, p) || len(request.Source.RequiredStatuses) > 0 || len(request.Source.RequiredCheckRuns) > 0
+		if request.Source.EveryCommit && request.Version.PR != "" && !gated {
+			if pushed == nil {
+				pushed, err = pushedCommits(ctx, request, manager, p)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to list commits: %s", err)
 				}
 			}
+			response = append(response, commitVersions(request.Source, p, pushed)...)
+		}
 
-			if !labelFound {
-				continue Loop
+		version := NewVersion(p)
+		version.CommittedDate = date
+		if trigger != nil && trigger.Type == githubv4.PullRequestTimelineItemsItemTypeIssueComment {
+			version.CommentID = strconv.FormatInt(trigger.CommentID, 10)
+			version.CommentAuthor = trigger.Actor
+		}
+		if request.Source.TriggerOnBaseChange {
+			version.BaseCommit = p.BaseRef.Target.OID
+		}
+		response = append(response, version)
+	}
+
+	sort.Strings(stillConflicting)
+	for i := range response {
+		response[i].Conflicting = strings.Join(stillConflicting, ",")
+	}
+
+	// Sort the commits by date, keeping the order of commits with the same date
+	sort.Stable(response)
+
+	LoggerFromContext(ctx).Debug("check", Fields{
+		"version":       request.Version,
+		"pull_requests": len(pulls),
+		"versions":      len(response),
+		"duration_ms":   milliseconds(start),
+	})
+
+	// If there are no new but an old version = return the old
+	if len(response) == 0 && request.Version.PR != "" {
+		response = append(response, request.Version)
+	}
+	// If there are new versions and no previous = return just the latest
+	if len(response) != 0 && request.Version.PR == "" {
+		response = CheckResponse{response[len(response)-1]}
+	}
+	return response, decisions, nil
+}
+
+// versionDate returns the date of the version for a pull request: the latest of
+// when it was updated, when it was triggered or approved (by the given timeline
+// items, if any) and when its required statuses and check runs completed. The tip
+// of open pull requests is dated by its commit date unless byPush is set.
+func versionDate(s Source, p *PullRequest, byPush bool, items ...*TimelineItem) time.Time {
+	date := p.UpdatedDate().Time
+	if !byPush && p.State == githubv4.PullRequestStateOpen {
+		date = p.Tip.CommittedDate.Time
+	}
+	for _, item := range items {
+		if item != nil && item.CreatedAt.After(date) {
+			date = item.CreatedAt
+		}
+	}
+	if p.State == githubv4.PullRequestStateOpen {
+		for _, completed := range []time.Time{statusDate(p.Statuses, s.RequiredStatuses), statusDate(p.CheckRuns, s.RequiredCheckRuns)} {
+			if completed.After(date) {
+				date = completed
 			}
 		}
+	}
+	return date
+}
+
+// baseDate returns when a change of the base branch of an open pull request was
+// seen, if it triggers a new version, and zero otherwise. With trigger_on_base_change
+// that is when the head of the base branch was committed, or now if the head
+// differs from the base commit of the current version (on the same base branch).
+// With ignore_conflicting, pull requests which were conflicting in the current
+// version and are now mergeable are dated now, since Github does not tell when
+// their conflicts were resolved.
+func baseDate(request CheckRequest, p *PullRequest, versionBase string, conflicting map[string]bool, now time.Time) time.Time {
+	switch {
+	case p.State != githubv4.PullRequestStateOpen:
+		return time.Time{}
+	case request.Source.TriggerOnBaseChange && versionBase != "" && p.BaseRefName == versionBase && p.BaseRef.Target.OID != request.Version.BaseCommit:
+		return now
+	case request.Source.TriggerOnBaseChange:
+		return p.BaseRef.Target.Commit.CommittedDate.Time
+	case request.Source.IgnoreConflicting && conflicting[strconv.Itoa(p.Number)] && p.Mergeable == githubv4.MergeableStateMergeable:
+		return now
+	}
+	return time.Time{}
+}
+
+// evaluatePullRequest runs the filters from the source against a pull request,
+// with the date of the version it would produce and the compiled regular
+// expressions of the source.
+func evaluatePullRequest(request CheckRequest, p *PullRequest, date time.Time, regexps map[string]*regexp.Regexp) (Decision, error) {
+	d := Decision{PR: p.Number, Commit: p.Tip.OID}
+	reject := func(filter, format string, a ...interface{}) (Decision, error) {
+		d.Filter = filter
+		d.Reason = fmt.Sprintf(format, a...)
+		return d, nil
+	}
+
+	disableSkipCI := request.Source.DisableCISkip
+	skipCIPatterns := request.Source.skipCIPatterns()
+
+	// [ci skip]/[skip ci] in Pull request title
+	if pattern, ok := matchSkipCI(skipCIPatterns, p.Title); !disableSkipCI && ok {
+		return reject("disable_ci_skip", "title contains %s", pattern)
+	}
 
-		// Filter out forks.
-		if request.Source.DisableForks && p.IsCrossRepository {
+	// [ci skip]/[skip ci] in Pull request body
+	if pattern, ok := matchSkipCI(skipCIPatterns, p.Body); !disableSkipCI && request.Source.SkipCIBody && ok {
+		return reject("disable_ci_skip", "body contains %s", pattern)
+	}
+
+	// [ci skip]/[skip ci] in Commit message (with skip_ci_all_commits, Evaluate checks every new commit instead)
+	if pattern, ok := matchSkipCI(skipCIPatterns, p.Tip.Message); !disableSkipCI && !request.Source.SkipCIAllCommits && ok {
+		return reject("disable_ci_skip", "commit message contains %s", pattern)
+	}
+
+	// Filter pull request if the BaseBranch does not match any of the patterns specified in source
+	if len(request.Source.BaseBranch) > 0 && !matchPatterns(request.Source.BaseBranch, p.BaseRefName) {
+		return reject("base_branch", "base branch %s does not match %s", p.BaseRefName, strings.Join(request.Source.BaseBranch, ", "))
+	}
+
+	// Filter pull request by the regular expressions specified in source
+	for _, f := range []struct {
+		name, description, value string
+		ignore                   bool
+	}{
+		{"base_branch_regex", "base branch " + p.BaseRefName, p.BaseRefName, false},
+		{"ignore_base_branch_regex", "base branch " + p.BaseRefName, p.BaseRefName, true},
+		{"head_branch", "head branch " + p.HeadRefName, p.HeadRefName, false},
+		{"ignore_head_branch", "head branch " + p.HeadRefName, p.HeadRefName, true},
+		{"title_regex", "title", p.Title, false},
+		{"ignore_title_regex", "title", p.Title, true},
+		{"body_regex", "body", p.Body, false},
+		{"ignore_body_regex", "body", p.Body, true},
+	} {
+		re, ok := regexps[f.name]
+		if !ok {
 			continue
 		}
+		match := re.MatchString(f.value)
+		if match && f.ignore {
+			return reject(f.name, "%s matches %s", f.description, f.name)
+		}
+		if !match && !f.ignore {
+			return reject(f.name, "%s does not match %s", f.description, f.name)
+		}
+	}
 
-		// Filter out drafts.
-		if request.Source.IgnoreDrafts && p.IsDraft {
-			continue
+	// Filter out commits that are too old (and which have not been triggered since the current version).
+	if !date.After(request.Version.CommittedDate) {
+		return reject("version", "not updated since the current version")
+	}
+
+	// Filter out pull request if it does not contain at least one (or all) of the desired labels
+	if len(request.Source.Labels) > 0 {
+		labelFound := false
+		for _, wantedLabel := range request.Source.Labels {
+			_, found := matchLabel(p.Labels, wantedLabel)
+			if request.Source.LabelMatch == LabelMatchAll && !found {
+				return reject("labels", "does not have the label %s", wantedLabel)
+			}
+			labelFound = labelFound || found
 		}
 
-		// Filter pull request if it does not have the required number of approved review(s).
-		if p.ApprovedReviewCount < request.Source.RequiredReviewApprovals {
-			continue
+		if !labelFound {
+			return reject("labels", "has none of the labels %s", strings.Join(request.Source.Labels, ", "))
 		}
+	}
 
-		// Fetch files once if paths/ignore_paths are specified.
-		var files []string
+	// Filter out pull request if it contains any of the ignored labels
+	for _, ignoredLabel := range request.Source.IgnoreLabels {
+		if name, found := matchLabel(p.Labels, ignoredLabel); found {
+			return reject("ignore_labels", "has the ignored label %s", name)
+		}
+	}
 
-		if len(request.Source.Paths) > 0 || len(request.Source.IgnorePaths) > 0 {
-			files, err = manager.ListModifiedFiles(p.Number)
-			if err != nil {
-				return nil, fmt.Errorf("failed to list modified files: %s", err)
+	// Filter out forks.
+	if request.Source.DisableForks && p.IsCrossRepository {
+		return reject("disable_forks", "opened from a fork")
+	}
+
+	// Filter out drafts.
+	if request.Source.IgnoreDrafts && p.IsDraft {
+		return reject("ignore_drafts", "is a draft")
+	}
+
+	// Filter out pull requests from untrusted authors.
+	if len(request.Source.AllowedAuthors) > 0 && !containsLogin(request.Source.AllowedAuthors, p.Author.Login) {
+		return reject("allowed_authors", "author %s is not in allowed_authors", p.Author.Login)
+	}
+	if containsLogin(request.Source.IgnoredAuthors, p.Author.Login) {
+		return reject("ignored_authors", "author %s is in ignored_authors", p.Author.Login)
+	}
+	if len(request.Source.AuthorAssociations) > 0 && !containsAssociation(request.Source.AuthorAssociations, p.AuthorAssociation) {
+		return reject("author_associations", "author association %s is not in author_associations", p.AuthorAssociation)
+	}
+
+	// Filter pull request if it does not have the required number of approved review(s).
+	if p.ApprovedReviewCount < request.Source.RequiredReviewApprovals {
+		return reject("required_review_approvals", "has %d of %d required approvals", p.ApprovedReviewCount, request.Source.RequiredReviewApprovals)
+	}
+
+	// Filter pull request if it has not been approved according to the branch protection rules.
+	if request.Source.RequireReviewDecision && p.ReviewDecision != githubv4.PullRequestReviewDecisionApproved {
+		decision := string(p.ReviewDecision)
+		if decision == "" {
+			decision = "not set"
+		}
+		return reject("require_review_decision", "review decision is %s", decision)
+	}
+
+	// Filter pull request if any reviewer has requested changes.
+	if request.Source.BlockChangesRequested && p.ChangesRequestedCount > 0 {
+		return reject("block_changes_requested", "%d reviewer(s) requested changes", p.ChangesRequestedCount)
+	}
+
+	// Filter pull request if any of the required statuses or check runs have not succeeded.
+	for _, f := range []struct {
+		name, description string
+		required          []string
+		statuses          []StatusObject
+	}{
+		{"required_statuses", "status", request.Source.RequiredStatuses, p.Statuses},
+		{"required_check_runs", "check run", request.Source.RequiredCheckRuns, p.CheckRuns},
+	} {
+		for _, name := range f.required {
+			if state := statusState(f.statuses, name); state != "SUCCESS" {
+				return reject(f.name, "%s %s is %s", f.description, name, state)
 			}
 		}
+	}
 
-		// Skip version if no files match the specified paths.
-		if len(request.Source.Paths) > 0 {
-			var wanted []string
-			for _, pattern := range request.Source.Paths {
-				w, err := FilterPath(files, pattern)
-				if err != nil {
-					return nil, fmt.Errorf("path match failed: %s", err)
-				}
-				wanted = append(wanted, w...)
+	// Filter pull request if it cannot be merged (mergeability which is still being calculated is not filtered).
+	if request.Source.IgnoreConflicting && p.Mergeable == githubv4.MergeableStateConflicting {
+		return reject("ignore_conflicting", "has conflicts with the base branch")
+	}
+	if request.Source.RequireUpToDate && p.MergeStateStatus == "BEHIND" {
+		return reject("require_up_to_date", "is behind the base branch")
+	}
+
+	var files []string
+	for _, f := range p.Files {
+		files = append(files, f.Path)
+	}
+
+	// Skip version if no files are selected by the specified paths.
+	if patterns := request.Source.PathPatterns(); len(patterns) > 0 {
+		filter, err := NewPathFilter(patterns)
+		if err != nil {
+			return d, fmt.Errorf("path match failed: %s", err)
+		}
+		if len(filter.Filter(files)) == 0 {
+			if len(request.Source.Paths) == 0 {
+				return reject("ignore_paths", "ignore_paths matched all %d files", len(files))
 			}
-			if len(wanted) == 0 {
-				continue Loop
+			return reject("paths", "paths matched none of %d files", len(files))
+		}
+	}
+
+	d.Accepted = true
+	return d, nil
+}
+
+// pushedCommits returns the commits pushed to a pull request since the current
+// version, oldest first, which is at least the tip.
+func pushedCommits(ctx context.Context, request CheckRequest, manager Github, p *PullRequest) ([]CommitObject, error) {
+	commits, err := manager.ListCommits(ctx, strconv.Itoa(p.Number))
+	if err != nil {
+		return nil, err
+	}
+
+	// Commits after the current version if it belongs to the pull request, otherwise
+	// the commits which were pushed after the current version.
+	var pushed []CommitObject
+	if request.Version.PR == strconv.Itoa(p.Number) {
+		for i, c := range commits {
+			if c.OID == request.Version.Commit {
+				pushed = commits[i+1:]
+				break
 			}
 		}
+	}
+	if pushed == nil {
+		for _, c := range commits {
+			if p.PushedDate(c).After(request.Version.CommittedDate) {
+				pushed = append(pushed, c)
+			}
+		}
+	}
+	if len(pushed) == 0 {
+		pushed = []CommitObject{p.Tip}
+	}
+	return pushed, nil
+}
 
-		// Skip version if all files are ignored.
-		if len(request.Source.IgnorePaths) > 0 {
-			wanted := files
-			for _, pattern := range request.Source.IgnorePaths {
-				wanted, err = FilterIgnorePath(wanted, pattern)
-				if err != nil {
-					return nil, fmt.Errorf("ignore path match failed: %s", err)
-				}
+// skippedCommits returns the number of pushed commits if all of them are marked
+// skip, and 0 if any of them is not.
+func skippedCommits(s Source, pushed []CommitObject) int {
+	patterns := s.skipCIPatterns()
+	for _, c := range pushed {
+		if _, ok := matchSkipCI(patterns, c.Message); !ok {
+			return 0
+		}
+	}
+	return len(pushed)
+}
+
+// commitVersions returns the versions for the pushed commits before the tip of a
+// pull request, except commits which are marked skip.
+func commitVersions(s Source, p *PullRequest, pushed []CommitObject) []Version {
+	var versions []Version
+	for _, c := range pushed {
+		if c.OID == p.Tip.OID {
+			continue
+		}
+		if _, ok := matchSkipCI(s.skipCIPatterns(), c.Message); ok && !s.DisableCISkip {
+			continue
+		}
+		pushed := p.PushedDate(c)
+		version := NewVersion(p)
+		version.Commit = c.OID
+		version.CommittedDate = pushed
+		version.PushedDate = &pushed
+		if s.TriggerOnBaseChange {
+			version.BaseCommit = p.BaseRef.Target.OID
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// requiresOkToTest returns true if a pull request must be marked ok-to-test
+// before it can be built, which is the case for pull requests from forks.
+func requiresOkToTest(s Source, p *PullRequest) bool {
+	return (s.OkToTestLabel != "" || s.OkToTestComment != "") && p.IsCrossRepository
+}
+
+// findOkToTest returns the first ok-to-test label or comment applied by a user
+// with write permission after the tip of the pull request was pushed, or the
+// first ok-to-test comment naming the tip (e.g. /ok-to-test 1a2b3c4). It returns
+// nil if the tip has not been approved.
+func findOkToTest(ctx context.Context, request CheckRequest, manager Github, p *PullRequest, permissions map[string]bool) (*TimelineItem, error) {
+	s := request.Source
+	items, err := manager.ListTimelineItems(ctx, strconv.Itoa(p.Number))
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the (last) push of the tip, so that approvals of earlier commits are not inherited.
+	pushed := -1
+	var pushedAt time.Time
+	for i, item := range items {
+		switch item.Type {
+		case githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent:
+			if item.Commit == p.Tip.OID {
+				pushed, pushedAt = i, item.CreatedAt
+			}
+		}
+	}
+
+	// Regular pushes to Github are not timed, and their position in the timeline follows the commit
+	// date (which is set by the author). Approvals of them are used up by the first version of
+	// another commit of the pull request built after them, so that a backdated push can not inherit them.
+	var used time.Time
+	if request.Version.PR == strconv.Itoa(p.Number) && request.Version.Commit != p.Tip.OID {
+		used = request.Version.CommittedDate
+	}
+
+	for i, item := range items {
+		switch {
+		case item.Type == githubv4.PullRequestTimelineItemsItemTypeIssueComment && s.OkToTestComment != "" && containsCommitCommand(item.Body, s.OkToTestComment, p.Tip.OID):
+			// Approvals naming the tip cannot be inherited by another commit.
+		case pushed < 0 || i <= pushed:
+			// Otherwise the approval must come after the push in the timeline.
+			continue
+		case !pushedAt.IsZero() && !item.CreatedAt.After(pushedAt):
+			// Timed pushes must also be approved after they were pushed.
+			continue
+		case pushedAt.IsZero() && !item.CreatedAt.After(used):
+			// Untimed pushes must be approved after the current version (see above).
+			continue
+		case item.Type == githubv4.PullRequestTimelineItemsItemTypeLabeledEvent && s.OkToTestLabel != "" && item.Label == s.OkToTestLabel:
+		case item.Type == githubv4.PullRequestTimelineItemsItemTypeIssueComment && s.OkToTestComment != "" && containsCommand(item.Body, s.OkToTestComment):
+		default:
+			continue
+		}
+
+		allowed, err := canWrite(ctx, manager, item.Actor, permissions)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// findTrigger returns the latest item on the timeline of a pull request since
+// the current version which triggers a new version, or nil if there is none:
+// a trigger label being applied, or a comment by a user with write permission
+// which matches the compiled comment_trigger (nil when it is not set).
+func findTrigger(ctx context.Context, request CheckRequest, manager Github, p *PullRequest, permissions map[string]bool, comment *regexp.Regexp) (*TimelineItem, error) {
+	for i := len(p.TimelineItems) - 1; i >= 0; i-- {
+		item := p.TimelineItems[i]
+		if !item.CreatedAt.After(request.Version.CommittedDate) {
+			continue
+		}
+		switch item.Type {
+		case githubv4.PullRequestTimelineItemsItemTypeLabeledEvent:
+			if matchPatterns(request.Source.TriggerLabels, item.Label) {
+				return &item, nil
+			}
+		case githubv4.PullRequestTimelineItemsItemTypeIssueComment:
+			if comment == nil || !comment.MatchString(item.Body) {
+				continue
+			}
+			allowed, err := canWrite(ctx, manager, item.Actor, permissions)
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				return &item, nil
 			}
-			if len(wanted) == 0 {
-				continue Loop
+		}
+	}
+	return nil, nil
+}
+
+// canWrite returns true if the user has write permission, caching the permissions for the duration of the check.
+func canWrite(ctx context.Context, manager Github, login string, permissions map[string]bool) (bool, error) {
+	if allowed, cached := permissions[login]; cached {
+		return allowed, nil
+	}
+	permission, err := manager.GetPermission(ctx, login)
+	if err != nil {
+		return false, err
+	}
+	permissions[login] = hasWritePermission(permission)
+	return permissions[login], nil
+}
+
+// containsCommand returns true if a line of the comment is the given command (e.g. /ok-to-test).
+func containsCommand(comment, command string) bool {
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.TrimSpace(line) == command {
+			return true
+		}
+	}
+	return false
+}
+
+// containsCommitCommand returns true if a line of the comment is the given command followed
+// by the commit SHA, or a prefix of at least 7 characters (e.g. /ok-to-test 1a2b3c4).
+func containsCommitCommand(comment, command, sha string) bool {
+	for _, line := range strings.Split(comment, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == command && len(fields[1]) >= 7 && strings.HasPrefix(sha, fields[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWritePermission returns true if the permission allows pushing to the repository.
+func hasWritePermission(permission string) bool {
+	switch permission {
+	case "owner", "admin", "maintain", "write":
+		return true
+	}
+	return false
+}
+
+// Decision records whether a pull request produced a new version in check,
+// and if not, which filter rejected it.
+type Decision struct {
+	PR       int    `json:"pr"`
+	Commit   string `json:"commit"`
+	Accepted bool   `json:"accepted"`
+	Filter   string `json:"filter,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// String returns a human readable description of the decision.
+func (d Decision) String() string {
+	if d.Accepted {
+		return fmt.Sprintf("PR #%d accepted", d.PR)
+	}
+	return fmt.Sprintf("PR #%d rejected: %s", d.PR, d.Reason)
+}
+
+// statusDate returns the latest time any of the statuses with the given names completed.
+func statusDate(statuses []StatusObject, names []string) time.Time {
+	var date time.Time
+	for _, s := range statuses {
+		for _, name := range names {
+			if s.Name == name && s.CompletedAt.After(date) {
+				date = s.CompletedAt
 			}
 		}
-		response = append(response, NewVersion(p))
 	}
+	return date
+}
+
+// statusState returns SUCCESS if all statuses with the given name succeeded,
+// the state of the first one which did not otherwise, or MISSING if there are none.
+func statusState(statuses []StatusObject, name string) string {
+	state := "MISSING"
+	for _, s := range statuses {
+		if s.Name != name {
+			continue
+		}
+		if s.State != "SUCCESS" {
+			return s.State
+		}
+		state = s.State
+	}
+	return state
+}
 
-	// Sort the commits by date
-	sort.Sort(response)
+// matchLabel returns the name of the first label matching the pattern (which
+// is validated by the source), and whether any label matched.
+func matchLabel(labels []LabelObject, pattern string) (string, bool) {
+	for _, l := range labels {
+		if match, _ := path.Match(pattern, l.Name); match {
+			return l.Name, true
+		}
+	}
+	return "", false
+}
 
-	// If there are no new but an old version = return the old
-	if len(response) == 0 && request.Version.PR != "" {
-		response = append(response, request.Version)
+// matchPatterns returns true if the name matches any of the patterns (which
+// are validated by the source).
+func matchPatterns(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match, _ := path.Match(pattern, name); match {
+			return true
+		}
 	}
-	// If there are new versions and no previous = return just the latest
-	if len(response) != 0 && request.Version.PR == "" {
-		response = CheckResponse{response[len(response)-1]}
+	return false
+}
+
+// containsLogin returns true if the login is in the list (logins are case insensitive).
+func containsLogin(logins []string, login string) bool {
+	for _, l := range logins {
+		if login != "" && strings.EqualFold(l, login) {
+			return true
+		}
 	}
-	return response, nil
+	return false
+}
+
+// containsAssociation returns true if the association is in the list.
+func containsAssociation(associations []githubv4.CommentAuthorAssociation, association githubv4.CommentAuthorAssociation) bool {
+	for _, a := range associations {
+		if a == association {
+			return true
+		}
+	}
+	return false
 }
 
+// defaultSkipCIPatterns are used unless skip_ci_patterns are specified in source.
+var defaultSkipCIPatterns = []string{"[ci skip]", "[skip ci]"}
+
 // ContainsSkipCI returns true if a string contains [ci skip] or [skip ci].
 func ContainsSkipCI(s string) bool {
-	re := regexp.MustCompile("(?i)\\[(ci skip|skip ci)\\]")
-	return re.MatchString(s)
+	_, ok := matchSkipCI(defaultSkipCIPatterns, s)
+	return ok
+}
+
+// matchSkipCI returns the first of the patterns which the string contains
+// (ignoring case), and whether any of them matched.
+func matchSkipCI(patterns []string, s string) (string, bool) {
+	s = strings.ToLower(s)
+	for _, pattern := range patterns {
+		if strings.Contains(s, strings.ToLower(pattern)) {
+			return pattern, true
+		}
+	}
+	return "", false
 }
 
 // FilterIgnorePath ...
+//
+// Deprecated: Check uses PathFilter, which supports gitignore-style patterns.
 func FilterIgnorePath(files []string, pattern string) ([]string, error) {
 	var out []string
 	for _, file := range files {
@@ -160,6 +760,8 @@ func FilterIgnorePath(files []string, pattern string) ([]string, error) {
 }
 
 // FilterPath ...
+//
+// Deprecated: Check uses PathFilter, which supports gitignore-style patterns.
 func FilterPath(files []string, pattern string) ([]string, error) {
 	var out []string
 	for _, file := range files {
@@ -1,7 +1,12 @@
 package resource_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/shurcooL/githubv4"
 	"github.com/stretchr/testify/assert"
@@ -88,6 +93,7 @@ func TestCheck(t *testing.T) {
 			version:      resource.NewVersion(testPullRequests[3]),
 			pullRequests: testPullRequests,
 			files: [][]string{
+				{},
 				{"README.md", "travis.yml"},
 				{"terraform/modules/ecs/main.tf", "README.md"},
 				{"terraform/modules/variables.tf", "travis.yml"},
@@ -107,6 +113,7 @@ func TestCheck(t *testing.T) {
 			version:      resource.NewVersion(testPullRequests[3]),
 			pullRequests: testPullRequests,
 			files: [][]string{
+				{},
 				{"README.md", "travis.yml"},
 				{"terraform/modules/ecs/main.tf", "README.md"},
 				{"terraform/modules/variables.tf", "travis.yml"},
@@ -180,7 +187,7 @@ func TestCheck(t *testing.T) {
 			source: resource.Source{
 				Repository:  "itsdalmo/test-repository",
 				AccessToken: "oauthtoken",
-				BaseBranch:  "develop",
+				BaseBranch:  resource.StringList{"develop"},
 			},
 			version:      resource.Version{},
 			pullRequests: testPullRequests,
@@ -273,26 +280,1314 @@ func TestCheck(t *testing.T) {
 				filterStates = tc.source.States
 			}
 			for i := range tc.pullRequests {
+				pr := *tc.pullRequests[i]
+				if i < len(tc.files) {
+					for _, f := range tc.files[i] {
+						pr.Files = append(pr.Files, resource.ChangedFileObject{Path: f})
+					}
+				}
 				for j := range filterStates {
-					if filterStates[j] == tc.pullRequests[i].PullRequestObject.State {
-						pullRequests = append(pullRequests, tc.pullRequests[i])
+					if filterStates[j] == pr.PullRequestObject.State {
+						pullRequests = append(pullRequests, &pr)
 						break
 					}
 				}
 			}
 			github.ListPullRequestsReturns(pullRequests, nil)
 
-			for i, file := range tc.files {
-				github.ListModifiedFilesReturnsOnCall(i, file, nil)
+			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
+			output, err := resource.Check(context.TODO(), input, github)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, output)
+			}
+			if assert.Equal(t, 1, github.ListPullRequestsCallCount()) {
+				_, _, includeFiles, since := github.ListPullRequestsArgsForCall(0)
+				assert.Equal(t, len(tc.source.Paths) > 0 || len(tc.source.IgnorePaths) > 0, includeFiles)
+				assert.Equal(t, tc.version.CommittedDate, since)
+			}
+		})
+	}
+}
+
+func TestCheckDecisions(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		version     resource.Version
+		pullRequest *resource.PullRequest
+		author      string
+		association githubv4.CommentAuthorAssociation
+		title       string
+		body        string
+		labels      []string
+		files       []string
+		decision    githubv4.PullRequestReviewDecision
+		changes     int
+		statuses    []resource.StatusObject
+		checkRuns   []resource.StatusObject
+		mergeable   githubv4.MergeableState
+		mergeState  string
+		expected    string
+	}{
+		{
+			description: "accepts pull requests which pass all filters",
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains skip ci",
+			pullRequest: testPullRequests[0],
+			expected:    "PR #1 rejected: commit message contains [skip ci]",
+		},
+		{
+			description: "explains custom skip ci patterns",
+			source:      resource.Source{SkipCIPatterns: []string{"***NO_CI***", "[no-build]"}},
+			pullRequest: testPullRequests[1],
+			title:       "docs: typos [No-Build]",
+			expected:    "PR #2 rejected: title contains [no-build]",
+		},
+		{
+			description: "ignores the default skip ci patterns when custom patterns are set",
+			source:      resource.Source{SkipCIPatterns: []string{"[no-build]"}},
+			pullRequest: testPullRequests[0],
+			expected:    "PR #1 accepted",
+		},
+		{
+			description: "explains skip ci in the body",
+			source:      resource.Source{SkipCIBody: true},
+			pullRequest: testPullRequests[1],
+			body:        "Only docs.\n\n[ci skip]",
+			expected:    "PR #2 rejected: body contains [ci skip]",
+		},
+		{
+			description: "ignores skip ci in the body by default",
+			pullRequest: testPullRequests[1],
+			body:        "[ci skip]",
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains base branch",
+			source:      resource.Source{BaseBranch: resource.StringList{"master"}},
+			pullRequest: testPullRequests[6],
+			expected:    "PR #7 rejected: base branch develop does not match master",
+		},
+		{
+			description: "accepts base branches matching any pattern",
+			source:      resource.Source{BaseBranch: resource.StringList{"master", "release/*"}},
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains base branch patterns",
+			source:      resource.Source{BaseBranch: resource.StringList{"main", "release/*"}},
+			pullRequest: testPullRequests[6],
+			expected:    "PR #7 rejected: base branch develop does not match main, release/*",
+		},
+		{
+			description: "accepts head branches matching head_branch",
+			source:      resource.Source{HeadBranch: "^pr[0-9]+$"},
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains head_branch",
+			source:      resource.Source{HeadBranch: "^feature/"},
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 rejected: head branch pr2 does not match head_branch",
+		},
+		{
+			description: "explains ignore_base_branch_regex",
+			source:      resource.Source{BaseBranchRegex: "^(master|develop)$", IgnoreBaseBranchRegex: "^develop$"},
+			pullRequest: testPullRequests[6],
+			expected:    "PR #7 rejected: base branch develop matches ignore_base_branch_regex",
+		},
+		{
+			description: "explains ignore_title_regex",
+			source:      resource.Source{IgnoreTitleRegex: "(?i)^pr2"},
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 rejected: title matches ignore_title_regex",
+		},
+		{
+			description: "explains body_regex",
+			source:      resource.Source{BodyRegex: "(?m)^Fixes #[0-9]+"},
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 rejected: body does not match body_regex",
+		},
+		{
+			description: "explains old versions",
+			version:     resource.NewVersion(testPullRequests[1]),
+			pullRequest: testPullRequests[2],
+			expected:    "PR #3 rejected: not updated since the current version",
+		},
+		{
+			description: "explains labels",
+			source:      resource.Source{Labels: []string{"bug", "enhancement"}},
+			pullRequest: testPullRequests[7],
+			expected:    "PR #8 rejected: has none of the labels bug, enhancement",
+		},
+		{
+			description: "matches labels with glob patterns",
+			source:      resource.Source{Labels: []string{"area/*"}},
+			pullRequest: testPullRequests[1],
+			labels:      []string{"area/backend"},
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "accepts pull requests with all labels",
+			source:      resource.Source{Labels: []string{"backend", "ready-for-ci"}, LabelMatch: "all"},
+			pullRequest: testPullRequests[1],
+			labels:      []string{"ready-for-ci", "backend", "bug"},
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains missing labels when matching all",
+			source:      resource.Source{Labels: []string{"backend", "ready-for-ci"}, LabelMatch: "all"},
+			pullRequest: testPullRequests[1],
+			labels:      []string{"backend"},
+			expected:    "PR #2 rejected: does not have the label ready-for-ci",
+		},
+		{
+			description: "explains ignored labels",
+			source:      resource.Source{Labels: []string{"backend"}, IgnoreLabels: []string{"do-not-build", "wip*"}},
+			pullRequest: testPullRequests[1],
+			labels:      []string{"backend", "wip: tests"},
+			expected:    "PR #2 rejected: has the ignored label wip: tests",
+		},
+		{
+			description: "explains forks",
+			source:      resource.Source{DisableForks: true},
+			pullRequest: testPullRequests[4],
+			expected:    "PR #5 rejected: opened from a fork",
+		},
+		{
+			description: "explains drafts",
+			source:      resource.Source{IgnoreDrafts: true},
+			pullRequest: testPullRequests[2],
+			expected:    "PR #3 rejected: is a draft",
+		},
+		{
+			description: "accepts allowed authors regardless of case",
+			source:      resource.Source{AllowedAuthors: []string{"itsdalmo"}},
+			pullRequest: testPullRequests[1],
+			author:      "ItsDalmo",
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains allowed authors",
+			source:      resource.Source{AllowedAuthors: []string{"itsdalmo"}},
+			pullRequest: testPullRequests[1],
+			author:      "someone",
+			expected:    "PR #2 rejected: author someone is not in allowed_authors",
+		},
+		{
+			description: "explains ignored authors",
+			source:      resource.Source{IgnoredAuthors: []string{"dependabot"}},
+			pullRequest: testPullRequests[1],
+			author:      "dependabot",
+			expected:    "PR #2 rejected: author dependabot is in ignored_authors",
+		},
+		{
+			description: "accepts allowed author associations",
+			source:      resource.Source{AuthorAssociations: []githubv4.CommentAuthorAssociation{githubv4.CommentAuthorAssociationOwner, githubv4.CommentAuthorAssociationMember}},
+			pullRequest: testPullRequests[1],
+			association: githubv4.CommentAuthorAssociationMember,
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains author associations",
+			source:      resource.Source{AuthorAssociations: []githubv4.CommentAuthorAssociation{githubv4.CommentAuthorAssociationOwner, githubv4.CommentAuthorAssociationMember}},
+			pullRequest: testPullRequests[1],
+			association: githubv4.CommentAuthorAssociationFirstTimeContributor,
+			expected:    "PR #2 rejected: author association FIRST_TIME_CONTRIBUTOR is not in author_associations",
+		},
+		{
+			description: "explains approvals",
+			source:      resource.Source{RequiredReviewApprovals: 2},
+			pullRequest: testPullRequests[7],
+			expected:    "PR #8 rejected: has 1 of 2 required approvals",
+		},
+		{
+			description: "accepts approved review decisions",
+			source:      resource.Source{RequireReviewDecision: true},
+			pullRequest: testPullRequests[1],
+			decision:    githubv4.PullRequestReviewDecisionApproved,
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains review decisions",
+			source:      resource.Source{RequireReviewDecision: true},
+			pullRequest: testPullRequests[1],
+			decision:    githubv4.PullRequestReviewDecisionReviewRequired,
+			expected:    "PR #2 rejected: review decision is REVIEW_REQUIRED",
+		},
+		{
+			description: "explains missing review decisions",
+			source:      resource.Source{RequireReviewDecision: true},
+			pullRequest: testPullRequests[1],
+			expected:    "PR #2 rejected: review decision is not set",
+		},
+		{
+			description: "explains requested changes",
+			source:      resource.Source{BlockChangesRequested: true},
+			pullRequest: testPullRequests[1],
+			changes:     2,
+			expected:    "PR #2 rejected: 2 reviewer(s) requested changes",
+		},
+		{
+			description: "accepts successful statuses and check runs",
+			source:      resource.Source{RequiredStatuses: []string{"lint"}, RequiredCheckRuns: []string{"build"}},
+			pullRequest: testPullRequests[1],
+			statuses:    []resource.StatusObject{{Name: "lint", State: "SUCCESS"}, {Name: "test", State: "FAILURE"}},
+			checkRuns:   []resource.StatusObject{{Name: "build", State: "SUCCESS"}},
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains pending statuses",
+			source:      resource.Source{RequiredStatuses: []string{"lint"}},
+			pullRequest: testPullRequests[1],
+			statuses:    []resource.StatusObject{{Name: "lint", State: "PENDING"}},
+			expected:    "PR #2 rejected: status lint is PENDING",
+		},
+		{
+			description: "explains missing statuses",
+			source:      resource.Source{RequiredStatuses: []string{"lint"}},
+			pullRequest: testPullRequests[1],
+			checkRuns:   []resource.StatusObject{{Name: "lint", State: "SUCCESS"}},
+			expected:    "PR #2 rejected: status lint is MISSING",
+		},
+		{
+			description: "explains failed check runs",
+			source:      resource.Source{RequiredCheckRuns: []string{"build"}},
+			pullRequest: testPullRequests[1],
+			checkRuns:   []resource.StatusObject{{Name: "build", State: "SUCCESS"}, {Name: "build", State: "FAILURE"}},
+			expected:    "PR #2 rejected: check run build is FAILURE",
+		},
+		{
+			description: "accepts pull requests while mergeability is calculated",
+			source:      resource.Source{IgnoreConflicting: true, RequireUpToDate: true},
+			pullRequest: testPullRequests[1],
+			mergeable:   githubv4.MergeableStateUnknown,
+			mergeState:  "UNKNOWN",
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains conflicts",
+			source:      resource.Source{IgnoreConflicting: true},
+			pullRequest: testPullRequests[1],
+			mergeable:   githubv4.MergeableStateConflicting,
+			mergeState:  "DIRTY",
+			expected:    "PR #2 rejected: has conflicts with the base branch",
+		},
+		{
+			description: "explains pull requests which are not up to date",
+			source:      resource.Source{RequireUpToDate: true},
+			pullRequest: testPullRequests[1],
+			mergeable:   githubv4.MergeableStateMergeable,
+			mergeState:  "BEHIND",
+			expected:    "PR #2 rejected: is behind the base branch",
+		},
+		{
+			description: "explains paths",
+			source:      resource.Source{Paths: []string{"terraform/*/*.tf"}},
+			pullRequest: testPullRequests[1],
+			files:       []string{"README.md", "test/main.go"},
+			expected:    "PR #2 rejected: paths matched none of 2 files",
+		},
+		{
+			description: "accepts files selected by gitignore-style paths",
+			source:      resource.Source{Paths: []string{"services/", "!docs/"}},
+			pullRequest: testPullRequests[1],
+			files:       []string{"services/api/docs/api.md", "services/api/main.go"},
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "explains negated paths",
+			source:      resource.Source{Paths: []string{"services/", "!**/docs/"}},
+			pullRequest: testPullRequests[1],
+			files:       []string{"services/api/docs/api.md", "README.md"},
+			expected:    "PR #2 rejected: paths matched none of 2 files",
+		},
+		{
+			description: "explains ignore paths",
+			source:      resource.Source{IgnorePaths: []string{"*.md", "docs/"}},
+			pullRequest: testPullRequests[1],
+			files:       []string{"README.md", "CHANGELOG.md", "docs/a.md", "docs/b.md"},
+			expected:    "PR #2 rejected: ignore_paths matched all 4 files",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *tc.pullRequest
+			pr.Author.Login = tc.author
+			pr.AuthorAssociation = tc.association
+			if tc.title != "" {
+				pr.Title = tc.title
+			}
+			pr.Body = tc.body
+			pr.ReviewDecision = tc.decision
+			pr.ChangesRequestedCount = tc.changes
+			pr.Statuses = tc.statuses
+			pr.CheckRuns = tc.checkRuns
+			pr.Mergeable = tc.mergeable
+			pr.MergeStateStatus = tc.mergeState
+			if tc.labels != nil {
+				pr.Labels = nil
+				for _, l := range tc.labels {
+					pr.Labels = append(pr.Labels, resource.LabelObject{Name: l})
+				}
+			}
+			for _, f := range tc.files {
+				pr.Files = append(pr.Files, resource.ChangedFileObject{Path: f})
 			}
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
 
 			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
-			output, err := resource.Check(input, github)
+			_, decisions, err := resource.Evaluate(context.TODO(), input, github)
+			if assert.NoError(t, err) && assert.Len(t, decisions, 1) {
+				assert.Equal(t, tc.expected, decisions[0].String())
+				assert.Equal(t, pr.Tip.OID, decisions[0].Commit)
+			}
+		})
+	}
+}
+
+func TestCheckExplain(t *testing.T) {
+	github := new(fakes.FakeGithub)
+	github.ListPullRequestsReturns([]*resource.PullRequest{testPullRequests[1], testPullRequests[4]}, nil)
 
+	source := resource.Source{
+		Repository:   "itsdalmo/test-repository",
+		AccessToken:  "oauthtoken",
+		DisableForks: true,
+		Explain:      true,
+	}
+	var out bytes.Buffer
+	ctx := resource.WithLogger(context.TODO(), resource.NewLogger(&source, &out))
+
+	_, err := resource.Check(ctx, resource.CheckRequest{Source: source}, github)
+	assert.NoError(t, err)
+
+	var lines []struct {
+		Msg      string
+		Decision resource.Decision
+	}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var line struct {
+			Msg      string
+			Decision resource.Decision
+		}
+		if assert.NoError(t, decoder.Decode(&line)) {
+			lines = append(lines, line)
+		}
+	}
+
+	if assert.Len(t, lines, 2, "expected one decision per pull request (and no debug logs)") {
+		assert.Equal(t, "PR #2 accepted", lines[0].Msg)
+		assert.True(t, lines[0].Decision.Accepted)
+		assert.Equal(t, "PR #5 rejected: opened from a fork", lines[1].Msg)
+		assert.Equal(t, "disable_forks", lines[1].Decision.Filter)
+	}
+}
+
+func TestCheckOkToTest(t *testing.T) {
+	pushed := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	push := resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, CreatedAt: pushed, Commit: "oid1"}
+	forcePush := resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent, CreatedAt: pushed.Add(2 * time.Hour), Commit: "oid1"}
+	label := func(login string, at time.Time) resource.TimelineItem {
+		return resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypeLabeledEvent, CreatedAt: at, Actor: login, Label: "ok-to-test"}
+	}
+	comment := func(login, body string, at time.Time) resource.TimelineItem {
+		return resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypeIssueComment, CreatedAt: at, Actor: login, Body: body}
+	}
+
+	tests := []struct {
+		description string
+		source      resource.Source
+		fork        bool
+		version     resource.Version
+		timeline    []resource.TimelineItem
+		tip         string
+		expected    bool
+	}{
+		{
+			description: "does not gate pull requests from the same repository",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			expected:    true,
+		},
+		{
+			description: "gates forks without an ok-to-test",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push},
+		},
+		{
+			description: "accepts a label from a maintainer after the push",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push, label("maintainer", pushed.Add(time.Hour))},
+			expected:    true,
+		},
+		{
+			description: "ignores a label from a user without write permission",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push, label("contributor", pushed.Add(time.Hour))},
+		},
+		{
+			description: "ignores a label applied before the tip was pushed",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{label("maintainer", pushed.Add(-time.Hour)), push},
+		},
+		{
+			description: "ignores a label applied before the tip was force pushed",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push, label("maintainer", pushed.Add(time.Hour)), forcePush},
+		},
+		{
+			description: "ignores a label which comes later in the timeline but is older than the push",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push, label("maintainer", pushed.Add(-time.Hour))},
+		},
+		{
+			description: "accepts a comment command from a maintainer",
+			source:      resource.Source{OkToTestComment: "/ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push, comment("maintainer", "Looks safe.\r\n/ok-to-test\n", pushed.Add(time.Hour))},
+			expected:    true,
+		},
+		{
+			description: "ignores comments which only mention the command",
+			source:      resource.Source{OkToTestComment: "/ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{push, comment("maintainer", "please /ok-to-test", pushed.Add(time.Hour))},
+		},
+		{
+			description: "accepts a label after an untimed push",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline: []resource.TimelineItem{
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "oid1"},
+				label("maintainer", pushed.Add(time.Hour)),
+			},
+			expected: true,
+		},
+		{
+			description: "ignores a label used by a version of another commit after an untimed push of a backdated commit",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			version:     resource.Version{PR: "2", Commit: "good", CommittedDate: pushed.Add(2 * time.Hour)},
+			timeline: []resource.TimelineItem{
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "good"},
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "oid1"},
+				label("maintainer", pushed.Add(2*time.Hour)),
+			},
+		},
+		{
+			description: "accepts a comment command after a version of another commit",
+			source:      resource.Source{OkToTestComment: "/ok-to-test"},
+			fork:        true,
+			version:     resource.Version{PR: "2", Commit: "good", CommittedDate: pushed.Add(2 * time.Hour)},
+			timeline: []resource.TimelineItem{
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "good"},
+				label("maintainer", pushed.Add(2*time.Hour)),
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "oid1"},
+				comment("maintainer", "/ok-to-test", pushed.Add(3*time.Hour)),
+			},
+			expected: true,
+		},
+		{
+			description: "accepts a comment command naming the tip after an untimed push",
+			source:      resource.Source{OkToTestComment: "/ok-to-test"},
+			fork:        true,
+			timeline: []resource.TimelineItem{
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "oid1abcdef"},
+				comment("maintainer", "/ok-to-test oid1abc", pushed.Add(time.Hour)),
+			},
+			tip:      "oid1abcdef",
+			expected: true,
+		},
+		{
+			description: "ignores a comment command naming another commit",
+			source:      resource.Source{OkToTestComment: "/ok-to-test"},
+			fork:        true,
+			timeline: []resource.TimelineItem{
+				push,
+				comment("maintainer", "/ok-to-test good123", pushed.Add(-time.Hour)),
+			},
+		},
+		{
+			description: "ignores a comment command naming the tip by a short prefix",
+			source:      resource.Source{OkToTestComment: "/ok-to-test"},
+			fork:        true,
+			timeline: []resource.TimelineItem{
+				{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "oid1abcdef"},
+				comment("maintainer", "/ok-to-test oid1", pushed.Add(time.Hour)),
+			},
+			tip: "oid1abcdef",
+		},
+		{
+			description: "gates forks whose tip is not on the timeline",
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			timeline:    []resource.TimelineItem{label("maintainer", pushed.Add(time.Hour))},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.OID = "oid1"
+			if tc.tip != "" {
+				pr.Tip.OID = tc.tip
+			}
+			pr.IsCrossRepository = tc.fork
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+			github.ListTimelineItemsReturns(tc.timeline, nil)
+			github.GetPermissionStub = func(_ context.Context, login string) (string, error) {
+				if login == "maintainer" {
+					return "write", nil
+				}
+				return "read", nil
+			}
+
+			_, decisions, err := resource.Evaluate(context.TODO(), resource.CheckRequest{Source: tc.source, Version: tc.version}, github)
+			if assert.NoError(t, err) && assert.Len(t, decisions, 1) {
+				assert.Equal(t, tc.expected, decisions[0].Accepted, decisions[0].String())
+			}
+			if !tc.fork {
+				assert.Equal(t, 0, github.ListTimelineItemsCallCount())
+			}
+		})
+	}
+
+	t.Run("dates the version by the approval", func(t *testing.T) {
+		pr := *testPullRequests[1]
+		pr.Tip.OID = "oid1"
+		pr.Tip.CommittedDate = githubv4.DateTime{Time: pushed}
+		pr.IsCrossRepository = true
+
+		github := new(fakes.FakeGithub)
+		github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+		github.ListTimelineItemsReturns([]resource.TimelineItem{push, label("maintainer", pushed.Add(2*time.Hour))}, nil)
+		github.GetPermissionReturns("write", nil)
+
+		built := pushed.Add(time.Hour)
+		input := resource.CheckRequest{
+			Source:  resource.Source{OkToTestLabel: "ok-to-test"},
+			Version: resource.Version{PR: "1", Commit: "other", CommittedDate: built, PushedDate: &built},
+		}
+		output, err := resource.Check(context.TODO(), input, github)
+		if assert.NoError(t, err) && assert.Len(t, output, 1) {
+			assert.Equal(t, "2", output[0].PR)
+			assert.Equal(t, pushed.Add(2*time.Hour), output[0].CommittedDate)
+		}
+	})
+}
+
+func TestCheckRequiredStatusesDate(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		source      resource.Source
+		statuses    []resource.StatusObject
+		checkRuns   []resource.StatusObject
+		expected    time.Time
+	}{
+		{
+			description: "emits a version when the required status succeeds after the current version",
+			source:      resource.Source{RequiredStatuses: []string{"lint"}},
+			statuses: []resource.StatusObject{
+				{Name: "lint", State: "SUCCESS", CompletedAt: built.Add(time.Hour)},
+				{Name: "other", State: "SUCCESS", CompletedAt: built.Add(2 * time.Hour)},
+			},
+			expected: built.Add(time.Hour),
+		},
+		{
+			description: "dates the version by the last required check run",
+			source:      resource.Source{RequiredCheckRuns: []string{"build", "test"}},
+			checkRuns: []resource.StatusObject{
+				{Name: "build", State: "SUCCESS", CompletedAt: built.Add(-time.Hour)},
+				{Name: "test", State: "SUCCESS", CompletedAt: built.Add(2 * time.Hour)},
+			},
+			expected: built.Add(2 * time.Hour),
+		},
+		{
+			description: "ignores statuses which succeeded before the current version",
+			source:      resource.Source{RequiredStatuses: []string{"lint"}},
+			statuses:    []resource.StatusObject{{Name: "lint", State: "SUCCESS", CompletedAt: built.Add(-time.Hour)}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.CommittedDate = githubv4.DateTime{Time: built.Add(-2 * time.Hour)}
+			pr.Statuses = tc.statuses
+			pr.CheckRuns = tc.checkRuns
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+
+			current := resource.Version{PR: "1", Commit: "other", CommittedDate: built, PushedDate: &built}
+			output, err := resource.Check(context.TODO(), resource.CheckRequest{Source: tc.source, Version: current}, github)
+			if assert.NoError(t, err) && assert.Len(t, output, 1) {
+				if tc.expected.IsZero() {
+					assert.Equal(t, current, output[0])
+				} else {
+					assert.Equal(t, "2", output[0].PR)
+					assert.Equal(t, tc.expected, output[0].CommittedDate)
+				}
+			}
+
+			if assert.Equal(t, 1, github.ListPullRequestsCallCount()) {
+				_, _, _, since := github.ListPullRequestsArgsForCall(0)
+				assert.True(t, since.IsZero())
+			}
+		})
+	}
+}
+
+func TestCheckMergeableAfterConflicts(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		conflicting string
+		mergeable   githubv4.MergeableState
+		pushed      time.Time
+		expected    []string
+		recorded    string
+	}{
+		{
+			description: "records pull requests which are conflicting in new versions",
+			mergeable:   githubv4.MergeableStateConflicting,
+			pushed:      built.Add(time.Hour),
+			expected:    []string{"3"},
+			recorded:    "2",
+		},
+		{
+			description: "keeps recording pull requests which are still conflicting",
+			conflicting: "2",
+			mergeable:   githubv4.MergeableStateConflicting,
+			pushed:      built.Add(-time.Hour),
+			expected:    []string{"3"},
+			recorded:    "2",
+		},
+		{
+			description: "keeps recording pull requests while their mergeability is unknown",
+			conflicting: "2",
+			mergeable:   githubv4.MergeableStateUnknown,
+			pushed:      built.Add(-time.Hour),
+			expected:    []string{"3"},
+			recorded:    "2",
+		},
+		{
+			description: "emits a version when the conflicts of a recorded pull request are resolved",
+			conflicting: "2",
+			mergeable:   githubv4.MergeableStateMergeable,
+			pushed:      built.Add(-time.Hour),
+			expected:    []string{"3", "2"},
+		},
+		{
+			description: "does not emit mergeable pull requests which were not conflicting",
+			mergeable:   githubv4.MergeableStateMergeable,
+			pushed:      built.Add(-time.Hour),
+			expected:    []string{"3"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.CommittedDate = githubv4.DateTime{Time: tc.pushed}
+			pr.Mergeable = tc.mergeable
+			pr.BaseRef.Target.Commit.CommittedDate = githubv4.DateTime{Time: built.Add(time.Hour)}
+
+			other := *testPullRequests[1]
+			other.Number = 3
+			other.Tip.OID = "oid3"
+			other.Tip.CommittedDate = githubv4.DateTime{Time: built.Add(2 * time.Hour)}
+			other.Mergeable = githubv4.MergeableStateMergeable
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr, &other}, nil)
+
+			current := resource.Version{PR: "1", Commit: "other", CommittedDate: built, PushedDate: &built, Conflicting: tc.conflicting}
+			input := resource.CheckRequest{Source: resource.Source{IgnoreConflicting: true}, Version: current}
+			output, err := resource.Check(context.TODO(), input, github)
 			if assert.NoError(t, err) {
-				assert.Equal(t, tc.expected, output)
+				var actual []string
+				for _, v := range output {
+					actual = append(actual, v.PR)
+					assert.Equal(t, tc.recorded, v.Conflicting)
+				}
+				assert.Equal(t, tc.expected, actual)
+			}
+
+			if assert.Equal(t, 1, github.ListPullRequestsCallCount()) {
+				_, _, _, since := github.ListPullRequestsArgsForCall(0)
+				assert.Equal(t, tc.conflicting == "", since.Equal(built))
+			}
+		})
+	}
+}
+
+func TestCheckSkipCIAllCommits(t *testing.T) {
+	commit := func(oid, message string, day int) resource.CommitObject {
+		return resource.CommitObject{OID: oid, Message: message, CommittedDate: githubv4.DateTime{Time: time.Date(2020, 1, day, 0, 0, 0, 0, time.UTC)}}
+	}
+	commits := []resource.CommitObject{
+		commit("oid1", "feat: add a feature", 1),
+		commit("oid2", "fix: lint", 2),
+		commit("oid3", "docs: update [skip ci]", 3),
+	}
+
+	tests := []struct {
+		description string
+		version     resource.Version
+		commits     []resource.CommitObject
+		expected    string
+	}{
+		{
+			description: "builds if any commit since the current version of the pull request is not skipped",
+			version:     resource.Version{PR: "2", Commit: "oid1", CommittedDate: commits[0].CommittedDate.Time},
+			commits:     commits,
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "skips if all commits since the current version of the pull request are skipped",
+			version:     resource.Version{PR: "2", Commit: "oid2", CommittedDate: commits[1].CommittedDate.Time},
+			commits:     commits,
+			expected:    "PR #2 rejected: all 1 commit(s) since the current version are marked skip",
+		},
+		{
+			description: "uses the commit date if the current version is another pull request",
+			version:     resource.Version{PR: "1", Commit: "other", CommittedDate: commits[0].CommittedDate.Time},
+			commits:     commits,
+			expected:    "PR #2 accepted",
+		},
+		{
+			description: "skips if all commits are skipped",
+			commits:     []resource.CommitObject{commit("oid2", "[ci skip] wip", 2), commits[2]},
+			expected:    "PR #2 rejected: all 2 commit(s) since the current version are marked skip",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip = tc.commits[len(tc.commits)-1]
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+			github.ListCommitsReturns(tc.commits, nil)
+
+			input := resource.CheckRequest{Source: resource.Source{SkipCIAllCommits: true}, Version: tc.version}
+			_, decisions, err := resource.Evaluate(context.TODO(), input, github)
+			if assert.NoError(t, err) && assert.Len(t, decisions, 1) {
+				assert.Equal(t, tc.expected, decisions[0].String())
+			}
+			if assert.Equal(t, 1, github.ListCommitsCallCount()) {
+				_, number := github.ListCommitsArgsForCall(0)
+				assert.Equal(t, "2", number)
+			}
+		})
+	}
+
+	t.Run("does not list commits unless the tip is skipped", func(t *testing.T) {
+		github := new(fakes.FakeGithub)
+		github.ListPullRequestsReturns([]*resource.PullRequest{testPullRequests[1]}, nil)
+
+		input := resource.CheckRequest{Source: resource.Source{SkipCIAllCommits: true}}
+		_, decisions, err := resource.Evaluate(context.TODO(), input, github)
+		if assert.NoError(t, err) && assert.Len(t, decisions, 1) {
+			assert.True(t, decisions[0].Accepted)
+		}
+		assert.Equal(t, 0, github.ListCommitsCallCount())
+	})
+}
+
+func TestCheckPushedDate(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	committed := built.Add(-24 * time.Hour)
+	forcePush := func(at time.Time) resource.TimelineItem {
+		return resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent, CreatedAt: at, Actor: "author", Commit: "oid2"}
+	}
+
+	tests := []struct {
+		description string
+		version     resource.Version
+		timeline    []resource.TimelineItem
+		expected    time.Time
+	}{
+		{
+			description: "emits a version for a tip pushed after the current version",
+			version:     resource.Version{PR: "1", Commit: "other", CommittedDate: built, PushedDate: &built},
+			timeline:    []resource.TimelineItem{forcePush(built.Add(-48 * time.Hour)), forcePush(built.Add(time.Hour))},
+			expected:    built.Add(time.Hour),
+		},
+		{
+			description: "ignores a tip pushed before the current version",
+			version:     resource.Version{PR: "1", Commit: "other", CommittedDate: built, PushedDate: &built},
+			timeline:    []resource.TimelineItem{forcePush(built.Add(-time.Hour))},
+		},
+		{
+			description: "ignores pushes of other commits",
+			version:     resource.Version{PR: "1", Commit: "other", CommittedDate: built, PushedDate: &built},
+			timeline: []resource.TimelineItem{{
+				Type:      githubv4.PullRequestTimelineItemsItemTypePullRequestCommit,
+				CreatedAt: built.Add(time.Hour),
+				Commit:    "oid3",
+			}},
+		},
+		{
+			description: "compares versions without a push date by commit date",
+			version:     resource.Version{PR: "2", Commit: "oid2", CommittedDate: committed},
+			timeline:    []resource.TimelineItem{forcePush(built.Add(time.Hour))},
+		},
+		{
+			description: "emits versions with a push date after versions without one",
+			version:     resource.Version{PR: "1", Commit: "other", CommittedDate: committed.Add(-time.Hour)},
+			timeline:    []resource.TimelineItem{forcePush(built.Add(time.Hour))},
+			expected:    built.Add(time.Hour),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.CommittedDate = githubv4.DateTime{Time: committed}
+			pr.TimelineItems = tc.timeline
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+
+			input := resource.CheckRequest{Version: tc.version}
+			output, err := resource.Check(context.TODO(), input, github)
+			if assert.NoError(t, err) && assert.Len(t, output, 1) {
+				if tc.expected.IsZero() {
+					assert.Equal(t, tc.version, output[0])
+					return
+				}
+				assert.Equal(t, "2", output[0].PR)
+				assert.Equal(t, "oid2", output[0].Commit)
+				assert.Equal(t, tc.expected, output[0].CommittedDate)
+				if assert.NotNil(t, output[0].PushedDate) {
+					assert.Equal(t, tc.expected, *output[0].PushedDate)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckEveryCommit(t *testing.T) {
+	commit := func(oid, message string, day int) resource.CommitObject {
+		return resource.CommitObject{OID: oid, Message: message, CommittedDate: githubv4.DateTime{Time: time.Date(2020, 1, day, 0, 0, 0, 0, time.UTC)}}
+	}
+	commits := []resource.CommitObject{
+		commit("oid1", "feat: add a feature", 1),
+		commit("oid2", "fix: lint", 2),
+		commit("oid3", "docs: update [skip ci]", 3),
+		commit("oid4", "fix: tests", 4),
+	}
+	pushed := commits[len(commits)-1].CommittedDate.Time
+
+	tests := []struct {
+		description string
+		version     resource.Version
+		source      resource.Source
+		fork        bool
+		expected    []string
+	}{
+		{
+			description: "emits every commit since the current version of the pull request",
+			version:     resource.Version{PR: "2", Commit: "oid1", CommittedDate: commits[0].CommittedDate.Time},
+			expected:    []string{"oid2", "oid4"},
+		},
+		{
+			description: "emits skipped commits if disable_ci_skip is set",
+			version:     resource.Version{PR: "2", Commit: "oid1", CommittedDate: commits[0].CommittedDate.Time},
+			source:      resource.Source{DisableCISkip: true},
+			expected:    []string{"oid2", "oid3", "oid4"},
+		},
+		{
+			description: "uses the commit date if the current version is another pull request",
+			version:     resource.Version{PR: "1", Commit: "other", CommittedDate: commits[1].CommittedDate.Time},
+			expected:    []string{"oid4"},
+		},
+		{
+			description: "emits only the latest commit without a current version",
+			expected:    []string{"oid4"},
+		},
+		{
+			description: "emits only the tip of forks which are approved with ok_to_test_label",
+			version:     resource.Version{PR: "2", Commit: "oid1", CommittedDate: commits[0].CommittedDate.Time},
+			source:      resource.Source{OkToTestLabel: "ok-to-test"},
+			fork:        true,
+			expected:    []string{"oid4"},
+		},
+		{
+			description: "emits only the tip with required_statuses",
+			version:     resource.Version{PR: "2", Commit: "oid1", CommittedDate: commits[0].CommittedDate.Time},
+			source:      resource.Source{RequiredStatuses: []string{"ci/build"}},
+			expected:    []string{"oid4"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip = commits[len(commits)-1]
+			pr.IsCrossRepository = tc.fork
+			pr.Statuses = []resource.StatusObject{{Name: "ci/build", State: "SUCCESS"}}
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+			github.ListCommitsReturns(commits, nil)
+			github.ListTimelineItemsReturns([]resource.TimelineItem{
+				{Type: githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent, CreatedAt: pushed, Commit: "oid4"},
+				{Type: githubv4.PullRequestTimelineItemsItemTypeLabeledEvent, CreatedAt: pushed.Add(time.Hour), Actor: "maintainer", Label: "ok-to-test"},
+			}, nil)
+			github.GetPermissionReturns("write", nil)
+
+			source := tc.source
+			source.EveryCommit = true
+			input := resource.CheckRequest{Source: source, Version: tc.version}
+			output, err := resource.Check(context.TODO(), input, github)
+			if assert.NoError(t, err) {
+				var actual []string
+				for _, v := range output {
+					actual = append(actual, v.Commit)
+					assert.Equal(t, "2", v.PR)
+				}
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckCommentTrigger(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	pushed := built.Add(-time.Hour)
+	comment := func(id int64, login, body string, at time.Time) resource.TimelineItem {
+		return resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypeIssueComment, CreatedAt: at, Actor: login, Body: body, CommentID: id}
+	}
+
+	tests := []struct {
+		description string
+		timeline    []resource.TimelineItem
+		expected    []resource.Version
+	}{
+		{
+			description: "emits a version for a comment from a collaborator",
+			timeline: []resource.TimelineItem{
+				comment(1, "maintainer", "/retest", built.Add(time.Hour)),
+				comment(2, "maintainer", "LGTM", built.Add(2*time.Hour)),
+			},
+			expected: []resource.Version{{
+				PR:                  "2",
+				Commit:              "oid1",
+				CommittedDate:       built.Add(time.Hour),
+				ApprovedReviewCount: "0",
+				State:               githubv4.PullRequestStateOpen,
+				CommentID:           "1",
+				CommentAuthor:       "maintainer",
+				PushedDate:          &pushed,
+			}},
+		},
+		{
+			description: "ignores comments from users without write permission",
+			timeline:    []resource.TimelineItem{comment(1, "contributor", "/retest", built.Add(time.Hour))},
+		},
+		{
+			description: "ignores comments before the current version",
+			timeline:    []resource.TimelineItem{comment(1, "maintainer", "/retest", built)},
+		},
+		{
+			description: "ignores comments which do not match",
+			timeline:    []resource.TimelineItem{comment(1, "maintainer", "please /retest", built.Add(time.Hour))},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.OID = "oid1"
+			pr.Tip.CommittedDate = githubv4.DateTime{Time: pushed}
+			pr.TimelineItems = tc.timeline
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+			github.GetPermissionStub = func(_ context.Context, login string) (string, error) {
+				if login == "maintainer" {
+					return "maintain", nil
+				}
+				return "read", nil
+			}
+
+			current := resource.Version{PR: "1", Commit: "other", CommittedDate: built}
+			input := resource.CheckRequest{Source: resource.Source{CommentTrigger: "^/(retest|build)$"}, Version: current}
+			output, _, err := resource.Evaluate(context.TODO(), input, github)
+			if assert.NoError(t, err) {
+				if tc.expected == nil {
+					tc.expected = []resource.Version{current}
+				}
+				assert.Equal(t, resource.CheckResponse(tc.expected), output)
+			}
+		})
+	}
+}
+
+func TestCheckTriggerLabels(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	label := func(name string, at time.Time) resource.TimelineItem {
+		return resource.TimelineItem{Type: githubv4.PullRequestTimelineItemsItemTypeLabeledEvent, CreatedAt: at, Actor: "someone", Label: name}
+	}
+
+	tests := []struct {
+		description string
+		timeline    []resource.TimelineItem
+		expected    time.Time
+	}{
+		{
+			description: "emits a version when a trigger label is applied",
+			timeline:    []resource.TimelineItem{label("deploy-preview", built.Add(time.Hour)), label("bug", built.Add(2*time.Hour))},
+			expected:    built.Add(time.Hour),
+		},
+		{
+			description: "matches trigger labels with glob patterns",
+			timeline:    []resource.TimelineItem{label("deploy/staging", built.Add(time.Hour))},
+			expected:    built.Add(time.Hour),
+		},
+		{
+			description: "ignores labels applied before the current version",
+			timeline:    []resource.TimelineItem{label("deploy-preview", built)},
+		},
+		{
+			description: "ignores other labels",
+			timeline:    []resource.TimelineItem{label("bug", built.Add(time.Hour))},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.CommittedDate = githubv4.DateTime{Time: built.Add(-time.Hour)}
+			pr.TimelineItems = tc.timeline
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+
+			current := resource.Version{PR: "1", Commit: "other", CommittedDate: built}
+			input := resource.CheckRequest{Source: resource.Source{TriggerLabels: []string{"deploy-preview", "deploy/*"}}, Version: current}
+			output, _, err := resource.Evaluate(context.TODO(), input, github)
+			if assert.NoError(t, err) && assert.Len(t, output, 1) {
+				if tc.expected.IsZero() {
+					assert.Equal(t, current, output[0])
+					return
+				}
+				assert.Equal(t, "2", output[0].PR)
+				assert.Equal(t, pr.Tip.OID, output[0].Commit)
+				assert.Equal(t, tc.expected, output[0].CommittedDate)
+				assert.Empty(t, output[0].CommentID)
+			}
+			assert.Equal(t, 0, github.GetPermissionCallCount())
+		})
+	}
+}
+
+func TestCheckTriggerOnBaseChange(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		enabled     bool
+		state       githubv4.PullRequestState
+		base        time.Time
+		expected    time.Time
+	}{
+		{
+			description: "emits a version when the base branch changes",
+			enabled:     true,
+			state:       githubv4.PullRequestStateOpen,
+			base:        built.Add(time.Hour),
+			expected:    built.Add(time.Hour),
+		},
+		{
+			description: "ignores base commits before the current version",
+			enabled:     true,
+			state:       githubv4.PullRequestStateOpen,
+			base:        built.Add(-time.Hour),
+		},
+		{
+			description: "ignores base changes for closed pull requests",
+			enabled:     true,
+			state:       githubv4.PullRequestStateClosed,
+			base:        built.Add(time.Hour),
+		},
+		{
+			description: "ignores base changes unless enabled",
+			state:       githubv4.PullRequestStateOpen,
+			base:        built.Add(time.Hour),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			pr := *testPullRequests[1]
+			pr.Tip.CommittedDate = githubv4.DateTime{Time: built.Add(-2 * time.Hour)}
+			pr.State = tc.state
+			pr.ClosedAt = pr.Tip.CommittedDate
+			pr.BaseRef.Target.OID = "base1"
+			pr.BaseRef.Target.Commit.CommittedDate = githubv4.DateTime{Time: tc.base}
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns([]*resource.PullRequest{&pr}, nil)
+
+			current := resource.Version{PR: "1", Commit: "other", CommittedDate: built}
+			input := resource.CheckRequest{
+				Source: resource.Source{
+					States:              []githubv4.PullRequestState{githubv4.PullRequestStateOpen, githubv4.PullRequestStateClosed},
+					TriggerOnBaseChange: tc.enabled,
+				},
+				Version: current,
+			}
+			output, _, err := resource.Evaluate(context.TODO(), input, github)
+			if assert.NoError(t, err) && assert.Len(t, output, 1) {
+				if tc.expected.IsZero() {
+					assert.Equal(t, current, output[0])
+				} else {
+					assert.Equal(t, "2", output[0].PR)
+					assert.Equal(t, pr.Tip.OID, output[0].Commit)
+					assert.Equal(t, tc.expected, output[0].CommittedDate)
+					assert.Equal(t, "base1", output[0].BaseCommit)
+				}
+			}
+
+			if assert.Equal(t, 1, github.ListPullRequestsCallCount()) {
+				_, _, _, since := github.ListPullRequestsArgsForCall(0)
+				if tc.enabled {
+					assert.True(t, since.IsZero())
+				} else {
+					assert.Equal(t, built, since)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckTriggerOnBaseChangeCommit(t *testing.T) {
+	built := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		base        string
+		expected    []string
+	}{
+		{
+			description: "emits versions when the base head changes to an older commit",
+			base:        "base2",
+			expected:    []string{"1", "2"},
+		},
+		{
+			description: "ignores an unchanged base head",
+			base:        "base1",
+			expected:    []string{"1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var pulls []*resource.PullRequest
+			for _, number := range []int{1, 2} {
+				pr := *testPullRequests[1]
+				pr.Number = number
+				pr.Tip.OID = "oid" + strconv.Itoa(number)
+				pr.Tip.CommittedDate = githubv4.DateTime{Time: built.Add(-2 * time.Hour)}
+				pr.BaseRef.Target.OID = tc.base
+				pr.BaseRef.Target.Commit.CommittedDate = githubv4.DateTime{Time: built.Add(-24 * time.Hour)}
+				pulls = append(pulls, &pr)
+			}
+
+			github := new(fakes.FakeGithub)
+			github.ListPullRequestsReturns(pulls, nil)
+
+			start := time.Now()
+			current := resource.Version{PR: "1", Commit: pulls[0].Tip.OID, CommittedDate: built, PushedDate: &built, BaseCommit: "base1"}
+			input := resource.CheckRequest{Source: resource.Source{TriggerOnBaseChange: true}, Version: current}
+			output, err := resource.Check(context.TODO(), input, github)
+			if assert.NoError(t, err) {
+				var actual []string
+				for _, v := range output {
+					actual = append(actual, v.PR)
+					if v.BaseCommit != current.BaseCommit {
+						assert.Equal(t, tc.base, v.BaseCommit)
+						assert.False(t, v.CommittedDate.Before(start))
+					}
+				}
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSourceValidateFilters(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		wantErr     string
+	}{
+		{
+			description: "accepts valid filters",
+			source:      resource.Source{Labels: []string{"area/*"}, LabelMatch: "all", TitleRegex: "^feat", IgnoreBodyRegex: "(?i)do not build"},
+		},
+		{
+			description: "rejects invalid regular expressions",
+			source:      resource.Source{IgnoreHeadBranch: "dependabot/(npm"},
+			wantErr:     "failed to compile ignore_head_branch: error parsing regexp: missing closing ): `dependabot/(npm`",
+		},
+		{
+			description: "rejects invalid label patterns",
+			source:      resource.Source{IgnoreLabels: []string{"area/["}},
+			wantErr:     "invalid label pattern \"area/[\": syntax error in pattern",
+		},
+		{
+			description: "rejects invalid base branch patterns",
+			source:      resource.Source{BaseBranch: resource.StringList{"main", "release/["}},
+			wantErr:     "invalid base_branch pattern \"release/[\": syntax error in pattern",
+		},
+		{
+			description: "rejects invalid comment triggers",
+			source:      resource.Source{CommentTrigger: "/(retest"},
+			wantErr:     "failed to compile comment_trigger: error parsing regexp: missing closing ): `/(retest`",
+		},
+		{
+			description: "rejects unknown label_match",
+			source:      resource.Source{LabelMatch: "some"},
+			wantErr:     "label_match \"some\" must be one of: any, all",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			tc.source.Repository = "itsdalmo/test-repository"
+			tc.source.AccessToken = "oauthtoken"
+			err := tc.source.Validate()
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
 			}
-			assert.Equal(t, 1, github.ListPullRequestsCallCount())
 		})
 	}
 }
@@ -550,3 +1845,46 @@ func TestIsInsidePath(t *testing.T) {
 		})
 	}
 }
+
+func TestSourceBaseBranch(t *testing.T) {
+	tests := []struct {
+		description string
+		source      string
+		expected    resource.StringList
+		wantErr     bool
+	}{
+		{
+			description: "accepts a single branch",
+			source:      `{"base_branch": "master"}`,
+			expected:    resource.StringList{"master"},
+		},
+		{
+			description: "accepts a list of patterns",
+			source:      `{"base_branch": ["main", "release/*"]}`,
+			expected:    resource.StringList{"main", "release/*"},
+		},
+		{
+			description: "treats an empty string as unset",
+			source:      `{"base_branch": ""}`,
+		},
+		{
+			description: "rejects other types",
+			source:      `{"base_branch": 1}`,
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var source resource.Source
+			err := json.Unmarshal([]byte(tc.source), &source)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, source.BaseBranch)
+			}
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 )
 
 func main() {
+	ctx, cancel := resource.NewSignalContext()
+	defer cancel()
+
 	var request resource.CheckRequest
 
 	decoder := json.NewDecoder(os.Stdin)
@@ -21,11 +24,12 @@ func main() {
 	if err := request.Source.Validate(); err != nil {
 		log.Fatalf("invalid source configuration: %s", err)
 	}
-	github, err := resource.NewGithubClient(&request.Source)
+	ctx = resource.WithLogger(ctx, resource.NewLogger(&request.Source, os.Stderr))
+	github, _, err := resource.NewManager(ctx, &request.Source)
 	if err != nil {
 		log.Fatalf("failed to create github manager: %s", err)
 	}
-	response, err := resource.Check(request, github)
+	response, err := resource.Check(ctx, request, github)
 	if err != nil {
 		log.Fatalf("check failed: %s", err)
 	}
@@ -0,0 +1,63 @@
+// Explain runs the check evaluation for a recorded check request (the JSON
+// which Concourse passes to check on stdin), and prints the decision made for
+// each pull request followed by the versions that check would emit.
+//
+// Usage: go run ./cmd/explain [request.json] (reads stdin if no file is given)
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/telia-oss/github-pr-resource"
+)
+
+func main() {
+	ctx, cancel := resource.NewSignalContext()
+	defer cancel()
+
+	var input io.Reader = os.Stdin
+	if len(os.Args) > 1 {
+		f, err := os.Open(os.Args[1])
+		if err != nil {
+			log.Fatalf("failed to open request: %s", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	var request resource.CheckRequest
+
+	decoder := json.NewDecoder(input)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&request); err != nil {
+		log.Fatalf("failed to unmarshal request: %s", err)
+	}
+
+	if err := request.Source.Validate(); err != nil {
+		log.Fatalf("invalid source configuration: %s", err)
+	}
+	ctx = resource.WithLogger(ctx, resource.NewLogger(&request.Source, os.Stderr))
+	github, _, err := resource.NewManager(ctx, &request.Source)
+	if err != nil {
+		log.Fatalf("failed to create github manager: %s", err)
+	}
+	response, decisions, err := resource.Evaluate(ctx, request, github)
+	if err != nil {
+		log.Fatalf("check failed: %s", err)
+	}
+
+	for _, d := range decisions {
+		fmt.Println(d)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(response); err != nil {
+		log.Fatalf("failed to marshal response: %s", err)
+	}
+}
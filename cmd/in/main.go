@@ -9,6 +9,9 @@ import (
 )
 
 func main() {
+	ctx, cancel := resource.NewSignalContext()
+	defer cancel()
+
 	var request resource.GetRequest
 
 	decoder := json.NewDecoder(os.Stdin)
@@ -25,15 +28,17 @@ func main() {
 	if err := request.Source.Validate(); err != nil {
 		log.Fatalf("invalid source configuration: %s", err)
 	}
-	git, err := resource.NewGitClient(&request.Source, outputDir, os.Stderr)
+	ctx = resource.WithLogger(ctx, resource.NewLogger(&request.Source, os.Stderr))
+	github, tokenSource, err := resource.NewManager(ctx, &request.Source)
 	if err != nil {
-		log.Fatalf("failed to create git client: %s", err)
+		log.Fatalf("failed to create github manager: %s", err)
 	}
-	github, err := resource.NewGithubClient(&request.Source)
+	git, err := resource.NewGitClient(&request.Source, tokenSource, outputDir, os.Stderr)
 	if err != nil {
-		log.Fatalf("failed to create github manager: %s", err)
+		log.Fatalf("failed to create git client: %s", err)
 	}
-	response, err := resource.Get(request, github, git, outputDir)
+	response, err := resource.Get(ctx, request, github, git, outputDir)
+	git.Close()
 	if err != nil {
 		log.Fatalf("get failed: %s", err)
 	}
@@ -9,6 +9,9 @@ import (
 )
 
 func main() {
+	ctx, cancel := resource.NewSignalContext()
+	defer cancel()
+
 	var request resource.PutRequest
 
 	decoder := json.NewDecoder(os.Stdin)
@@ -25,11 +28,12 @@ func main() {
 	if err := request.Source.Validate(); err != nil {
 		log.Fatalf("invalid source configuration: %s", err)
 	}
-	github, err := resource.NewGithubClient(&request.Source)
+	ctx = resource.WithLogger(ctx, resource.NewLogger(&request.Source, os.Stderr))
+	github, _, err := resource.NewManager(ctx, &request.Source)
 	if err != nil {
 		log.Fatalf("failed to create github manager: %s", err)
 	}
-	response, err := resource.Put(request, github, sourceDir)
+	response, err := resource.Put(ctx, request, github, sourceDir)
 	if err != nil {
 		log.Fatalf("put failed: %s", err)
 	}
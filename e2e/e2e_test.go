@@ -124,7 +124,7 @@ func TestCheckE2E(t *testing.T) {
 				AccessToken:   os.Getenv("GITHUB_ACCESS_TOKEN"),
 				V3Endpoint:    "https://api.github.com/",
 				V4Endpoint:    "https://api.github.com/graphql",
-				BaseBranch:    "develop",
+				BaseBranch:    resource.StringList{"develop"},
 				DisableCISkip: true,
 			},
 			version: resource.Version{},
@@ -177,11 +177,11 @@ func TestCheckE2E(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
-			githubClient, err := resource.NewGithubClient(&tc.source)
+			githubClient, err := resource.NewGithubClient(context.TODO(), &tc.source)
 			require.NoError(t, err)
 
 			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
-			output, err := resource.Check(input, githubClient)
+			output, err := resource.Check(context.TODO(), input, githubClient)
 
 			if assert.NoError(t, err) {
 				assert.Equal(t, tc.expected, output)
@@ -210,13 +210,13 @@ func TestCheckAPICostE2E(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
-			githubClient, err := resource.NewGithubClient(&tc.source)
+			githubClient, err := resource.NewGithubClient(context.TODO(), &tc.source)
 			require.NoError(t, err)
 
 			before := getRemainingRateLimit(t, githubClient.V4)
 
 			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
-			_, err = resource.Check(input, githubClient)
+			_, err = resource.Check(context.TODO(), input, githubClient)
 			require.NoError(t, err)
 
 			cost := before - getRemainingRateLimit(t, githubClient.V4)
@@ -420,15 +420,15 @@ func TestGetAndPutE2E(t *testing.T) {
 			require.NoError(t, err)
 			defer os.RemoveAll(dir)
 
-			githubClient, err := resource.NewGithubClient(&tc.source)
+			githubClient, err := resource.NewGithubClient(context.TODO(), &tc.source)
 			require.NoError(t, err)
 
-			git, err := resource.NewGitClient(&tc.source, dir, ioutil.Discard)
+			git, err := resource.NewGitClient(&tc.source, githubClient.TokenSource, dir, ioutil.Discard)
 			require.NoError(t, err)
 
 			// Get (output and files)
 			getRequest := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.getParameters}
-			getOutput, err := resource.Get(getRequest, githubClient, git, dir)
+			getOutput, err := resource.Get(context.TODO(), getRequest, githubClient, git, dir)
 
 			require.NoError(t, err)
 			assert.Equal(t, tc.version, getOutput.Version)
@@ -463,7 +463,7 @@ func TestGetAndPutE2E(t *testing.T) {
 
 			// Put
 			putRequest := resource.PutRequest{Source: tc.source, Params: tc.putParameters}
-			putOutput, err := resource.Put(putRequest, githubClient, dir)
+			putOutput, err := resource.Put(context.TODO(), putRequest, githubClient, dir)
 
 			require.NoError(t, err)
 			assert.Equal(t, tc.version, putOutput.Version)
@@ -525,15 +525,15 @@ func TestGetSubmodules(t *testing.T) {
 			require.NoError(t, err)
 			defer os.RemoveAll(dir)
 
-			githubClient, err := resource.NewGithubClient(&tc.source)
+			githubClient, err := resource.NewGithubClient(context.TODO(), &tc.source)
 			require.NoError(t, err)
 
-			git, err := resource.NewGitClient(&tc.source, dir, ioutil.Discard)
+			git, err := resource.NewGitClient(&tc.source, githubClient.TokenSource, dir, ioutil.Discard)
 			require.NoError(t, err)
 
 			// Get (output and files)
 			getRequest := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.getParameters}
-			_, err = resource.Get(getRequest, githubClient, git, dir)
+			_, err = resource.Get(context.TODO(), getRequest, githubClient, git, dir)
 			require.NoError(t, err)
 
 			files, err := ioutil.ReadDir(filepath.Join(dir, "submodule"))
@@ -619,10 +619,10 @@ func TestPutCommentsE2E(t *testing.T) {
 			require.NoError(t, err)
 			defer os.RemoveAll(dir)
 
-			githubClient, err := resource.NewGithubClient(&tc.source)
+			githubClient, err := resource.NewGithubClient(context.TODO(), &tc.source)
 			require.NoError(t, err)
 
-			git, err := resource.NewGitClient(&tc.source, dir, ioutil.Discard)
+			git, err := resource.NewGitClient(&tc.source, githubClient.TokenSource, dir, ioutil.Discard)
 			require.NoError(t, err)
 
 			pullRequest, _, err := githubClient.V3.PullRequests.Create(context.TODO(), owner, repository, &github.NewPullRequest{
@@ -643,7 +643,7 @@ func TestPutCommentsE2E(t *testing.T) {
 				PR:     strconv.Itoa(pullRequest.GetNumber()),
 				Commit: pullRequest.GetHead().GetSHA(),
 			}, Params: tc.getParams}
-			_, err = resource.Get(getRequest, githubClient, git, dir)
+			_, err = resource.Get(context.TODO(), getRequest, githubClient, git, dir)
 			require.NoError(t, err)
 
 			putRequest := resource.PutRequest{
@@ -651,7 +651,7 @@ func TestPutCommentsE2E(t *testing.T) {
 				Params: tc.putParameters,
 			}
 
-			_, err = resource.Put(putRequest, githubClient, dir)
+			_, err = resource.Put(context.TODO(), putRequest, githubClient, dir)
 			require.NoError(t, err)
 
 			comments, _, err := githubClient.V3.Issues.ListComments(context.TODO(), owner, repository, pullRequest.GetNumber(), nil)
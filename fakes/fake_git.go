@@ -2,18 +2,20 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	resource "github.com/telia-oss/github-pr-resource"
 )
 
 type FakeGit struct {
-	CheckoutStub        func(string, string, bool) error
+	CheckoutStub        func(context.Context, string, string, bool) error
 	checkoutMutex       sync.RWMutex
 	checkoutArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 bool
+		arg3 string
+		arg4 bool
 	}
 	checkoutReturns struct {
 		result1 error
@@ -21,13 +23,14 @@ type FakeGit struct {
 	checkoutReturnsOnCall map[int]struct {
 		result1 error
 	}
-	FetchStub        func(string, int, int, bool) error
+	FetchStub        func(context.Context, string, int, int, bool) error
 	fetchMutex       sync.RWMutex
 	fetchArgsForCall []struct {
-		arg1 string
-		arg2 int
+		arg1 context.Context
+		arg2 string
 		arg3 int
-		arg4 bool
+		arg4 int
+		arg5 bool
 	}
 	fetchReturns struct {
 		result1 error
@@ -35,10 +38,11 @@ type FakeGit struct {
 	fetchReturnsOnCall map[int]struct {
 		result1 error
 	}
-	GitCryptUnlockStub        func(string) error
+	GitCryptUnlockStub        func(context.Context, string) error
 	gitCryptUnlockMutex       sync.RWMutex
 	gitCryptUnlockArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	gitCryptUnlockReturns struct {
 		result1 error
@@ -46,10 +50,11 @@ type FakeGit struct {
 	gitCryptUnlockReturnsOnCall map[int]struct {
 		result1 error
 	}
-	InitStub        func(string) error
+	InitStub        func(context.Context, string) error
 	initMutex       sync.RWMutex
 	initArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	initReturns struct {
 		result1 error
@@ -57,11 +62,12 @@ type FakeGit struct {
 	initReturnsOnCall map[int]struct {
 		result1 error
 	}
-	MergeStub        func(string, bool) error
+	MergeStub        func(context.Context, string, bool) error
 	mergeMutex       sync.RWMutex
 	mergeArgsForCall []struct {
-		arg1 string
-		arg2 bool
+		arg1 context.Context
+		arg2 string
+		arg3 bool
 	}
 	mergeReturns struct {
 		result1 error
@@ -69,14 +75,15 @@ type FakeGit struct {
 	mergeReturnsOnCall map[int]struct {
 		result1 error
 	}
-	PullStub        func(string, string, int, bool, bool) error
+	PullStub        func(context.Context, string, string, int, bool, bool) error
 	pullMutex       sync.RWMutex
 	pullArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
-		arg4 bool
+		arg3 string
+		arg4 int
 		arg5 bool
+		arg6 bool
 	}
 	pullReturns struct {
 		result1 error
@@ -84,12 +91,13 @@ type FakeGit struct {
 	pullReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RebaseStub        func(string, string, bool) error
+	RebaseStub        func(context.Context, string, string, bool) error
 	rebaseMutex       sync.RWMutex
 	rebaseArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 bool
+		arg3 string
+		arg4 bool
 	}
 	rebaseReturns struct {
 		result1 error
@@ -97,10 +105,25 @@ type FakeGit struct {
 	rebaseReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RevParseStub        func(string) (string, error)
+	ResetStub        func(context.Context, string, int, bool) error
+	resetMutex       sync.RWMutex
+	resetArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+		arg4 bool
+	}
+	resetReturns struct {
+		result1 error
+	}
+	resetReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RevParseStub        func(context.Context, string) (string, error)
 	revParseMutex       sync.RWMutex
 	revParseArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	revParseReturns struct {
 		result1 string
@@ -114,18 +137,19 @@ type FakeGit struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeGit) Checkout(arg1 string, arg2 string, arg3 bool) error {
+func (fake *FakeGit) Checkout(arg1 context.Context, arg2 string, arg3 string, arg4 bool) error {
 	fake.checkoutMutex.Lock()
 	ret, specificReturn := fake.checkoutReturnsOnCall[len(fake.checkoutArgsForCall)]
 	fake.checkoutArgsForCall = append(fake.checkoutArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 bool
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("Checkout", []interface{}{arg1, arg2, arg3})
+		arg3 string
+		arg4 bool
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Checkout", []interface{}{arg1, arg2, arg3, arg4})
 	fake.checkoutMutex.Unlock()
 	if fake.CheckoutStub != nil {
-		return fake.CheckoutStub(arg1, arg2, arg3)
+		return fake.CheckoutStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -140,17 +164,17 @@ func (fake *FakeGit) CheckoutCallCount() int {
 	return len(fake.checkoutArgsForCall)
 }
 
-func (fake *FakeGit) CheckoutCalls(stub func(string, string, bool) error) {
+func (fake *FakeGit) CheckoutCalls(stub func(context.Context, string, string, bool) error) {
 	fake.checkoutMutex.Lock()
 	defer fake.checkoutMutex.Unlock()
 	fake.CheckoutStub = stub
 }
 
-func (fake *FakeGit) CheckoutArgsForCall(i int) (string, string, bool) {
+func (fake *FakeGit) CheckoutArgsForCall(i int) (context.Context, string, string, bool) {
 	fake.checkoutMutex.RLock()
 	defer fake.checkoutMutex.RUnlock()
 	argsForCall := fake.checkoutArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeGit) CheckoutReturns(result1 error) {
@@ -176,19 +200,20 @@ func (fake *FakeGit) CheckoutReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Fetch(arg1 string, arg2 int, arg3 int, arg4 bool) error {
+func (fake *FakeGit) Fetch(arg1 context.Context, arg2 string, arg3 int, arg4 int, arg5 bool) error {
 	fake.fetchMutex.Lock()
 	ret, specificReturn := fake.fetchReturnsOnCall[len(fake.fetchArgsForCall)]
 	fake.fetchArgsForCall = append(fake.fetchArgsForCall, struct {
-		arg1 string
-		arg2 int
+		arg1 context.Context
+		arg2 string
 		arg3 int
-		arg4 bool
-	}{arg1, arg2, arg3, arg4})
-	fake.recordInvocation("Fetch", []interface{}{arg1, arg2, arg3, arg4})
+		arg4 int
+		arg5 bool
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("Fetch", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.fetchMutex.Unlock()
 	if fake.FetchStub != nil {
-		return fake.FetchStub(arg1, arg2, arg3, arg4)
+		return fake.FetchStub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1
@@ -203,17 +228,17 @@ func (fake *FakeGit) FetchCallCount() int {
 	return len(fake.fetchArgsForCall)
 }
 
-func (fake *FakeGit) FetchCalls(stub func(string, int, int, bool) error) {
+func (fake *FakeGit) FetchCalls(stub func(context.Context, string, int, int, bool) error) {
 	fake.fetchMutex.Lock()
 	defer fake.fetchMutex.Unlock()
 	fake.FetchStub = stub
 }
 
-func (fake *FakeGit) FetchArgsForCall(i int) (string, int, int, bool) {
+func (fake *FakeGit) FetchArgsForCall(i int) (context.Context, string, int, int, bool) {
 	fake.fetchMutex.RLock()
 	defer fake.fetchMutex.RUnlock()
 	argsForCall := fake.fetchArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeGit) FetchReturns(result1 error) {
@@ -239,16 +264,17 @@ func (fake *FakeGit) FetchReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) GitCryptUnlock(arg1 string) error {
+func (fake *FakeGit) GitCryptUnlock(arg1 context.Context, arg2 string) error {
 	fake.gitCryptUnlockMutex.Lock()
 	ret, specificReturn := fake.gitCryptUnlockReturnsOnCall[len(fake.gitCryptUnlockArgsForCall)]
 	fake.gitCryptUnlockArgsForCall = append(fake.gitCryptUnlockArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("GitCryptUnlock", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("GitCryptUnlock", []interface{}{arg1, arg2})
 	fake.gitCryptUnlockMutex.Unlock()
 	if fake.GitCryptUnlockStub != nil {
-		return fake.GitCryptUnlockStub(arg1)
+		return fake.GitCryptUnlockStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -263,17 +289,17 @@ func (fake *FakeGit) GitCryptUnlockCallCount() int {
 	return len(fake.gitCryptUnlockArgsForCall)
 }
 
-func (fake *FakeGit) GitCryptUnlockCalls(stub func(string) error) {
+func (fake *FakeGit) GitCryptUnlockCalls(stub func(context.Context, string) error) {
 	fake.gitCryptUnlockMutex.Lock()
 	defer fake.gitCryptUnlockMutex.Unlock()
 	fake.GitCryptUnlockStub = stub
 }
 
-func (fake *FakeGit) GitCryptUnlockArgsForCall(i int) string {
+func (fake *FakeGit) GitCryptUnlockArgsForCall(i int) (context.Context, string) {
 	fake.gitCryptUnlockMutex.RLock()
 	defer fake.gitCryptUnlockMutex.RUnlock()
 	argsForCall := fake.gitCryptUnlockArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) GitCryptUnlockReturns(result1 error) {
@@ -299,16 +325,17 @@ func (fake *FakeGit) GitCryptUnlockReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Init(arg1 string) error {
+func (fake *FakeGit) Init(arg1 context.Context, arg2 string) error {
 	fake.initMutex.Lock()
 	ret, specificReturn := fake.initReturnsOnCall[len(fake.initArgsForCall)]
 	fake.initArgsForCall = append(fake.initArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("Init", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("Init", []interface{}{arg1, arg2})
 	fake.initMutex.Unlock()
 	if fake.InitStub != nil {
-		return fake.InitStub(arg1)
+		return fake.InitStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -323,17 +350,17 @@ func (fake *FakeGit) InitCallCount() int {
 	return len(fake.initArgsForCall)
 }
 
-func (fake *FakeGit) InitCalls(stub func(string) error) {
+func (fake *FakeGit) InitCalls(stub func(context.Context, string) error) {
 	fake.initMutex.Lock()
 	defer fake.initMutex.Unlock()
 	fake.InitStub = stub
 }
 
-func (fake *FakeGit) InitArgsForCall(i int) string {
+func (fake *FakeGit) InitArgsForCall(i int) (context.Context, string) {
 	fake.initMutex.RLock()
 	defer fake.initMutex.RUnlock()
 	argsForCall := fake.initArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) InitReturns(result1 error) {
@@ -359,17 +386,18 @@ func (fake *FakeGit) InitReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Merge(arg1 string, arg2 bool) error {
+func (fake *FakeGit) Merge(arg1 context.Context, arg2 string, arg3 bool) error {
 	fake.mergeMutex.Lock()
 	ret, specificReturn := fake.mergeReturnsOnCall[len(fake.mergeArgsForCall)]
 	fake.mergeArgsForCall = append(fake.mergeArgsForCall, struct {
-		arg1 string
-		arg2 bool
-	}{arg1, arg2})
-	fake.recordInvocation("Merge", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 bool
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("Merge", []interface{}{arg1, arg2, arg3})
 	fake.mergeMutex.Unlock()
 	if fake.MergeStub != nil {
-		return fake.MergeStub(arg1, arg2)
+		return fake.MergeStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -384,17 +412,17 @@ func (fake *FakeGit) MergeCallCount() int {
 	return len(fake.mergeArgsForCall)
 }
 
-func (fake *FakeGit) MergeCalls(stub func(string, bool) error) {
+func (fake *FakeGit) MergeCalls(stub func(context.Context, string, bool) error) {
 	fake.mergeMutex.Lock()
 	defer fake.mergeMutex.Unlock()
 	fake.MergeStub = stub
 }
 
-func (fake *FakeGit) MergeArgsForCall(i int) (string, bool) {
+func (fake *FakeGit) MergeArgsForCall(i int) (context.Context, string, bool) {
 	fake.mergeMutex.RLock()
 	defer fake.mergeMutex.RUnlock()
 	argsForCall := fake.mergeArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGit) MergeReturns(result1 error) {
@@ -420,20 +448,21 @@ func (fake *FakeGit) MergeReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Pull(arg1 string, arg2 string, arg3 int, arg4 bool, arg5 bool) error {
+func (fake *FakeGit) Pull(arg1 context.Context, arg2 string, arg3 string, arg4 int, arg5 bool, arg6 bool) error {
 	fake.pullMutex.Lock()
 	ret, specificReturn := fake.pullReturnsOnCall[len(fake.pullArgsForCall)]
 	fake.pullArgsForCall = append(fake.pullArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
-		arg4 bool
+		arg3 string
+		arg4 int
 		arg5 bool
-	}{arg1, arg2, arg3, arg4, arg5})
-	fake.recordInvocation("Pull", []interface{}{arg1, arg2, arg3, arg4, arg5})
+		arg6 bool
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.recordInvocation("Pull", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
 	fake.pullMutex.Unlock()
 	if fake.PullStub != nil {
-		return fake.PullStub(arg1, arg2, arg3, arg4, arg5)
+		return fake.PullStub(arg1, arg2, arg3, arg4, arg5, arg6)
 	}
 	if specificReturn {
 		return ret.result1
@@ -448,17 +477,17 @@ func (fake *FakeGit) PullCallCount() int {
 	return len(fake.pullArgsForCall)
 }
 
-func (fake *FakeGit) PullCalls(stub func(string, string, int, bool, bool) error) {
+func (fake *FakeGit) PullCalls(stub func(context.Context, string, string, int, bool, bool) error) {
 	fake.pullMutex.Lock()
 	defer fake.pullMutex.Unlock()
 	fake.PullStub = stub
 }
 
-func (fake *FakeGit) PullArgsForCall(i int) (string, string, int, bool, bool) {
+func (fake *FakeGit) PullArgsForCall(i int) (context.Context, string, string, int, bool, bool) {
 	fake.pullMutex.RLock()
 	defer fake.pullMutex.RUnlock()
 	argsForCall := fake.pullArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
 }
 
 func (fake *FakeGit) PullReturns(result1 error) {
@@ -484,18 +513,19 @@ func (fake *FakeGit) PullReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Rebase(arg1 string, arg2 string, arg3 bool) error {
+func (fake *FakeGit) Rebase(arg1 context.Context, arg2 string, arg3 string, arg4 bool) error {
 	fake.rebaseMutex.Lock()
 	ret, specificReturn := fake.rebaseReturnsOnCall[len(fake.rebaseArgsForCall)]
 	fake.rebaseArgsForCall = append(fake.rebaseArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 bool
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("Rebase", []interface{}{arg1, arg2, arg3})
+		arg3 string
+		arg4 bool
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Rebase", []interface{}{arg1, arg2, arg3, arg4})
 	fake.rebaseMutex.Unlock()
 	if fake.RebaseStub != nil {
-		return fake.RebaseStub(arg1, arg2, arg3)
+		return fake.RebaseStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -510,17 +540,17 @@ func (fake *FakeGit) RebaseCallCount() int {
 	return len(fake.rebaseArgsForCall)
 }
 
-func (fake *FakeGit) RebaseCalls(stub func(string, string, bool) error) {
+func (fake *FakeGit) RebaseCalls(stub func(context.Context, string, string, bool) error) {
 	fake.rebaseMutex.Lock()
 	defer fake.rebaseMutex.Unlock()
 	fake.RebaseStub = stub
 }
 
-func (fake *FakeGit) RebaseArgsForCall(i int) (string, string, bool) {
+func (fake *FakeGit) RebaseArgsForCall(i int) (context.Context, string, string, bool) {
 	fake.rebaseMutex.RLock()
 	defer fake.rebaseMutex.RUnlock()
 	argsForCall := fake.rebaseArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeGit) RebaseReturns(result1 error) {
@@ -546,16 +576,80 @@ func (fake *FakeGit) RebaseReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) RevParse(arg1 string) (string, error) {
+func (fake *FakeGit) Reset(arg1 context.Context, arg2 string, arg3 int, arg4 bool) error {
+	fake.resetMutex.Lock()
+	ret, specificReturn := fake.resetReturnsOnCall[len(fake.resetArgsForCall)]
+	fake.resetArgsForCall = append(fake.resetArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+		arg4 bool
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Reset", []interface{}{arg1, arg2, arg3, arg4})
+	fake.resetMutex.Unlock()
+	if fake.ResetStub != nil {
+		return fake.ResetStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.resetReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeGit) ResetCallCount() int {
+	fake.resetMutex.RLock()
+	defer fake.resetMutex.RUnlock()
+	return len(fake.resetArgsForCall)
+}
+
+func (fake *FakeGit) ResetCalls(stub func(context.Context, string, int, bool) error) {
+	fake.resetMutex.Lock()
+	defer fake.resetMutex.Unlock()
+	fake.ResetStub = stub
+}
+
+func (fake *FakeGit) ResetArgsForCall(i int) (context.Context, string, int, bool) {
+	fake.resetMutex.RLock()
+	defer fake.resetMutex.RUnlock()
+	argsForCall := fake.resetArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeGit) ResetReturns(result1 error) {
+	fake.resetMutex.Lock()
+	defer fake.resetMutex.Unlock()
+	fake.ResetStub = nil
+	fake.resetReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) ResetReturnsOnCall(i int, result1 error) {
+	fake.resetMutex.Lock()
+	defer fake.resetMutex.Unlock()
+	fake.ResetStub = nil
+	if fake.resetReturnsOnCall == nil {
+		fake.resetReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.resetReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) RevParse(arg1 context.Context, arg2 string) (string, error) {
 	fake.revParseMutex.Lock()
 	ret, specificReturn := fake.revParseReturnsOnCall[len(fake.revParseArgsForCall)]
 	fake.revParseArgsForCall = append(fake.revParseArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("RevParse", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("RevParse", []interface{}{arg1, arg2})
 	fake.revParseMutex.Unlock()
 	if fake.RevParseStub != nil {
-		return fake.RevParseStub(arg1)
+		return fake.RevParseStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -570,17 +664,17 @@ func (fake *FakeGit) RevParseCallCount() int {
 	return len(fake.revParseArgsForCall)
 }
 
-func (fake *FakeGit) RevParseCalls(stub func(string) (string, error)) {
+func (fake *FakeGit) RevParseCalls(stub func(context.Context, string) (string, error)) {
 	fake.revParseMutex.Lock()
 	defer fake.revParseMutex.Unlock()
 	fake.RevParseStub = stub
 }
 
-func (fake *FakeGit) RevParseArgsForCall(i int) string {
+func (fake *FakeGit) RevParseArgsForCall(i int) (context.Context, string) {
 	fake.revParseMutex.RLock()
 	defer fake.revParseMutex.RUnlock()
 	argsForCall := fake.revParseArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) RevParseReturns(result1 string, result2 error) {
@@ -626,6 +720,8 @@ func (fake *FakeGit) Invocations() map[string][][]interface{} {
 	defer fake.pullMutex.RUnlock()
 	fake.rebaseMutex.RLock()
 	defer fake.rebaseMutex.RUnlock()
+	fake.resetMutex.RLock()
+	defer fake.resetMutex.RUnlock()
 	fake.revParseMutex.RLock()
 	defer fake.revParseMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
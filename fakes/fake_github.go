@@ -2,17 +2,20 @@
 package fakes
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/shurcooL/githubv4"
 	resource "github.com/telia-oss/github-pr-resource"
 )
 
 type FakeGithub struct {
-	DeletePreviousCommentsStub        func(string) error
+	DeletePreviousCommentsStub        func(context.Context, string) error
 	deletePreviousCommentsMutex       sync.RWMutex
 	deletePreviousCommentsArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	deletePreviousCommentsReturns struct {
 		result1 error
@@ -20,11 +23,12 @@ type FakeGithub struct {
 	deletePreviousCommentsReturnsOnCall map[int]struct {
 		result1 error
 	}
-	GetChangedFilesStub        func(string, string) ([]resource.ChangedFileObject, error)
+	GetChangedFilesStub        func(context.Context, string, string) ([]resource.ChangedFileObject, error)
 	getChangedFilesMutex       sync.RWMutex
 	getChangedFilesArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	getChangedFilesReturns struct {
 		result1 []resource.ChangedFileObject
@@ -34,11 +38,26 @@ type FakeGithub struct {
 		result1 []resource.ChangedFileObject
 		result2 error
 	}
-	GetPullRequestStub        func(string, string) (*resource.PullRequest, error)
+	GetPermissionStub        func(context.Context, string) (string, error)
+	getPermissionMutex       sync.RWMutex
+	getPermissionArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getPermissionReturns struct {
+		result1 string
+		result2 error
+	}
+	getPermissionReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	GetPullRequestStub        func(context.Context, string, string) (*resource.PullRequest, error)
 	getPullRequestMutex       sync.RWMutex
 	getPullRequestArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	getPullRequestReturns struct {
 		result1 *resource.PullRequest
@@ -48,23 +67,27 @@ type FakeGithub struct {
 		result1 *resource.PullRequest
 		result2 error
 	}
-	ListModifiedFilesStub        func(int) ([]string, error)
-	listModifiedFilesMutex       sync.RWMutex
-	listModifiedFilesArgsForCall []struct {
-		arg1 int
+	ListCommitsStub        func(context.Context, string) ([]resource.CommitObject, error)
+	listCommitsMutex       sync.RWMutex
+	listCommitsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
 	}
-	listModifiedFilesReturns struct {
-		result1 []string
+	listCommitsReturns struct {
+		result1 []resource.CommitObject
 		result2 error
 	}
-	listModifiedFilesReturnsOnCall map[int]struct {
-		result1 []string
+	listCommitsReturnsOnCall map[int]struct {
+		result1 []resource.CommitObject
 		result2 error
 	}
-	ListPullRequestsStub        func([]githubv4.PullRequestState) ([]*resource.PullRequest, error)
+	ListPullRequestsStub        func(context.Context, []githubv4.PullRequestState, bool, time.Time) ([]*resource.PullRequest, error)
 	listPullRequestsMutex       sync.RWMutex
 	listPullRequestsArgsForCall []struct {
-		arg1 []githubv4.PullRequestState
+		arg1 context.Context
+		arg2 []githubv4.PullRequestState
+		arg3 bool
+		arg4 time.Time
 	}
 	listPullRequestsReturns struct {
 		result1 []*resource.PullRequest
@@ -74,11 +97,26 @@ type FakeGithub struct {
 		result1 []*resource.PullRequest
 		result2 error
 	}
-	PostCommentStub        func(string, string) error
+	ListTimelineItemsStub        func(context.Context, string) ([]resource.TimelineItem, error)
+	listTimelineItemsMutex       sync.RWMutex
+	listTimelineItemsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	listTimelineItemsReturns struct {
+		result1 []resource.TimelineItem
+		result2 error
+	}
+	listTimelineItemsReturnsOnCall map[int]struct {
+		result1 []resource.TimelineItem
+		result2 error
+	}
+	PostCommentStub        func(context.Context, string, string) error
 	postCommentMutex       sync.RWMutex
 	postCommentArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	postCommentReturns struct {
 		result1 error
@@ -86,15 +124,16 @@ type FakeGithub struct {
 	postCommentReturnsOnCall map[int]struct {
 		result1 error
 	}
-	UpdateCommitStatusStub        func(string, string, string, string, string, string) error
+	UpdateCommitStatusStub        func(context.Context, string, string, string, string, string, string) error
 	updateCommitStatusMutex       sync.RWMutex
 	updateCommitStatusArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
 		arg5 string
 		arg6 string
+		arg7 string
 	}
 	updateCommitStatusReturns struct {
 		result1 error
@@ -106,16 +145,17 @@ type FakeGithub struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeGithub) DeletePreviousComments(arg1 string) error {
+func (fake *FakeGithub) DeletePreviousComments(arg1 context.Context, arg2 string) error {
 	fake.deletePreviousCommentsMutex.Lock()
 	ret, specificReturn := fake.deletePreviousCommentsReturnsOnCall[len(fake.deletePreviousCommentsArgsForCall)]
 	fake.deletePreviousCommentsArgsForCall = append(fake.deletePreviousCommentsArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("DeletePreviousComments", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("DeletePreviousComments", []interface{}{arg1, arg2})
 	fake.deletePreviousCommentsMutex.Unlock()
 	if fake.DeletePreviousCommentsStub != nil {
-		return fake.DeletePreviousCommentsStub(arg1)
+		return fake.DeletePreviousCommentsStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -130,17 +170,17 @@ func (fake *FakeGithub) DeletePreviousCommentsCallCount() int {
 	return len(fake.deletePreviousCommentsArgsForCall)
 }
 
-func (fake *FakeGithub) DeletePreviousCommentsCalls(stub func(string) error) {
+func (fake *FakeGithub) DeletePreviousCommentsCalls(stub func(context.Context, string) error) {
 	fake.deletePreviousCommentsMutex.Lock()
 	defer fake.deletePreviousCommentsMutex.Unlock()
 	fake.DeletePreviousCommentsStub = stub
 }
 
-func (fake *FakeGithub) DeletePreviousCommentsArgsForCall(i int) string {
+func (fake *FakeGithub) DeletePreviousCommentsArgsForCall(i int) (context.Context, string) {
 	fake.deletePreviousCommentsMutex.RLock()
 	defer fake.deletePreviousCommentsMutex.RUnlock()
 	argsForCall := fake.deletePreviousCommentsArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGithub) DeletePreviousCommentsReturns(result1 error) {
@@ -166,17 +206,18 @@ func (fake *FakeGithub) DeletePreviousCommentsReturnsOnCall(i int, result1 error
 	}{result1}
 }
 
-func (fake *FakeGithub) GetChangedFiles(arg1 string, arg2 string) ([]resource.ChangedFileObject, error) {
+func (fake *FakeGithub) GetChangedFiles(arg1 context.Context, arg2 string, arg3 string) ([]resource.ChangedFileObject, error) {
 	fake.getChangedFilesMutex.Lock()
 	ret, specificReturn := fake.getChangedFilesReturnsOnCall[len(fake.getChangedFilesArgsForCall)]
 	fake.getChangedFilesArgsForCall = append(fake.getChangedFilesArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("GetChangedFiles", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("GetChangedFiles", []interface{}{arg1, arg2, arg3})
 	fake.getChangedFilesMutex.Unlock()
 	if fake.GetChangedFilesStub != nil {
-		return fake.GetChangedFilesStub(arg1, arg2)
+		return fake.GetChangedFilesStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -191,17 +232,17 @@ func (fake *FakeGithub) GetChangedFilesCallCount() int {
 	return len(fake.getChangedFilesArgsForCall)
 }
 
-func (fake *FakeGithub) GetChangedFilesCalls(stub func(string, string) ([]resource.ChangedFileObject, error)) {
+func (fake *FakeGithub) GetChangedFilesCalls(stub func(context.Context, string, string) ([]resource.ChangedFileObject, error)) {
 	fake.getChangedFilesMutex.Lock()
 	defer fake.getChangedFilesMutex.Unlock()
 	fake.GetChangedFilesStub = stub
 }
 
-func (fake *FakeGithub) GetChangedFilesArgsForCall(i int) (string, string) {
+func (fake *FakeGithub) GetChangedFilesArgsForCall(i int) (context.Context, string, string) {
 	fake.getChangedFilesMutex.RLock()
 	defer fake.getChangedFilesMutex.RUnlock()
 	argsForCall := fake.getChangedFilesArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGithub) GetChangedFilesReturns(result1 []resource.ChangedFileObject, result2 error) {
@@ -230,17 +271,82 @@ func (fake *FakeGithub) GetChangedFilesReturnsOnCall(i int, result1 []resource.C
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) GetPullRequest(arg1 string, arg2 string) (*resource.PullRequest, error) {
+func (fake *FakeGithub) GetPermission(arg1 context.Context, arg2 string) (string, error) {
+	fake.getPermissionMutex.Lock()
+	ret, specificReturn := fake.getPermissionReturnsOnCall[len(fake.getPermissionArgsForCall)]
+	fake.getPermissionArgsForCall = append(fake.getPermissionArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("GetPermission", []interface{}{arg1, arg2})
+	fake.getPermissionMutex.Unlock()
+	if fake.GetPermissionStub != nil {
+		return fake.GetPermissionStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getPermissionReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGithub) GetPermissionCallCount() int {
+	fake.getPermissionMutex.RLock()
+	defer fake.getPermissionMutex.RUnlock()
+	return len(fake.getPermissionArgsForCall)
+}
+
+func (fake *FakeGithub) GetPermissionCalls(stub func(context.Context, string) (string, error)) {
+	fake.getPermissionMutex.Lock()
+	defer fake.getPermissionMutex.Unlock()
+	fake.GetPermissionStub = stub
+}
+
+func (fake *FakeGithub) GetPermissionArgsForCall(i int) (context.Context, string) {
+	fake.getPermissionMutex.RLock()
+	defer fake.getPermissionMutex.RUnlock()
+	argsForCall := fake.getPermissionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeGithub) GetPermissionReturns(result1 string, result2 error) {
+	fake.getPermissionMutex.Lock()
+	defer fake.getPermissionMutex.Unlock()
+	fake.GetPermissionStub = nil
+	fake.getPermissionReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) GetPermissionReturnsOnCall(i int, result1 string, result2 error) {
+	fake.getPermissionMutex.Lock()
+	defer fake.getPermissionMutex.Unlock()
+	fake.GetPermissionStub = nil
+	if fake.getPermissionReturnsOnCall == nil {
+		fake.getPermissionReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.getPermissionReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) GetPullRequest(arg1 context.Context, arg2 string, arg3 string) (*resource.PullRequest, error) {
 	fake.getPullRequestMutex.Lock()
 	ret, specificReturn := fake.getPullRequestReturnsOnCall[len(fake.getPullRequestArgsForCall)]
 	fake.getPullRequestArgsForCall = append(fake.getPullRequestArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("GetPullRequest", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("GetPullRequest", []interface{}{arg1, arg2, arg3})
 	fake.getPullRequestMutex.Unlock()
 	if fake.GetPullRequestStub != nil {
-		return fake.GetPullRequestStub(arg1, arg2)
+		return fake.GetPullRequestStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -255,17 +361,17 @@ func (fake *FakeGithub) GetPullRequestCallCount() int {
 	return len(fake.getPullRequestArgsForCall)
 }
 
-func (fake *FakeGithub) GetPullRequestCalls(stub func(string, string) (*resource.PullRequest, error)) {
+func (fake *FakeGithub) GetPullRequestCalls(stub func(context.Context, string, string) (*resource.PullRequest, error)) {
 	fake.getPullRequestMutex.Lock()
 	defer fake.getPullRequestMutex.Unlock()
 	fake.GetPullRequestStub = stub
 }
 
-func (fake *FakeGithub) GetPullRequestArgsForCall(i int) (string, string) {
+func (fake *FakeGithub) GetPullRequestArgsForCall(i int) (context.Context, string, string) {
 	fake.getPullRequestMutex.RLock()
 	defer fake.getPullRequestMutex.RUnlock()
 	argsForCall := fake.getPullRequestArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGithub) GetPullRequestReturns(result1 *resource.PullRequest, result2 error) {
@@ -294,84 +400,88 @@ func (fake *FakeGithub) GetPullRequestReturnsOnCall(i int, result1 *resource.Pul
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) ListModifiedFiles(arg1 int) ([]string, error) {
-	fake.listModifiedFilesMutex.Lock()
-	ret, specificReturn := fake.listModifiedFilesReturnsOnCall[len(fake.listModifiedFilesArgsForCall)]
-	fake.listModifiedFilesArgsForCall = append(fake.listModifiedFilesArgsForCall, struct {
-		arg1 int
-	}{arg1})
-	fake.recordInvocation("ListModifiedFiles", []interface{}{arg1})
-	fake.listModifiedFilesMutex.Unlock()
-	if fake.ListModifiedFilesStub != nil {
-		return fake.ListModifiedFilesStub(arg1)
+func (fake *FakeGithub) ListCommits(arg1 context.Context, arg2 string) ([]resource.CommitObject, error) {
+	fake.listCommitsMutex.Lock()
+	ret, specificReturn := fake.listCommitsReturnsOnCall[len(fake.listCommitsArgsForCall)]
+	fake.listCommitsArgsForCall = append(fake.listCommitsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ListCommits", []interface{}{arg1, arg2})
+	fake.listCommitsMutex.Unlock()
+	if fake.ListCommitsStub != nil {
+		return fake.ListCommitsStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.listModifiedFilesReturns
+	fakeReturns := fake.listCommitsReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeGithub) ListModifiedFilesCallCount() int {
-	fake.listModifiedFilesMutex.RLock()
-	defer fake.listModifiedFilesMutex.RUnlock()
-	return len(fake.listModifiedFilesArgsForCall)
+func (fake *FakeGithub) ListCommitsCallCount() int {
+	fake.listCommitsMutex.RLock()
+	defer fake.listCommitsMutex.RUnlock()
+	return len(fake.listCommitsArgsForCall)
 }
 
-func (fake *FakeGithub) ListModifiedFilesCalls(stub func(int) ([]string, error)) {
-	fake.listModifiedFilesMutex.Lock()
-	defer fake.listModifiedFilesMutex.Unlock()
-	fake.ListModifiedFilesStub = stub
+func (fake *FakeGithub) ListCommitsCalls(stub func(context.Context, string) ([]resource.CommitObject, error)) {
+	fake.listCommitsMutex.Lock()
+	defer fake.listCommitsMutex.Unlock()
+	fake.ListCommitsStub = stub
 }
 
-func (fake *FakeGithub) ListModifiedFilesArgsForCall(i int) int {
-	fake.listModifiedFilesMutex.RLock()
-	defer fake.listModifiedFilesMutex.RUnlock()
-	argsForCall := fake.listModifiedFilesArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeGithub) ListCommitsArgsForCall(i int) (context.Context, string) {
+	fake.listCommitsMutex.RLock()
+	defer fake.listCommitsMutex.RUnlock()
+	argsForCall := fake.listCommitsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeGithub) ListModifiedFilesReturns(result1 []string, result2 error) {
-	fake.listModifiedFilesMutex.Lock()
-	defer fake.listModifiedFilesMutex.Unlock()
-	fake.ListModifiedFilesStub = nil
-	fake.listModifiedFilesReturns = struct {
-		result1 []string
+func (fake *FakeGithub) ListCommitsReturns(result1 []resource.CommitObject, result2 error) {
+	fake.listCommitsMutex.Lock()
+	defer fake.listCommitsMutex.Unlock()
+	fake.ListCommitsStub = nil
+	fake.listCommitsReturns = struct {
+		result1 []resource.CommitObject
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) ListModifiedFilesReturnsOnCall(i int, result1 []string, result2 error) {
-	fake.listModifiedFilesMutex.Lock()
-	defer fake.listModifiedFilesMutex.Unlock()
-	fake.ListModifiedFilesStub = nil
-	if fake.listModifiedFilesReturnsOnCall == nil {
-		fake.listModifiedFilesReturnsOnCall = make(map[int]struct {
-			result1 []string
+func (fake *FakeGithub) ListCommitsReturnsOnCall(i int, result1 []resource.CommitObject, result2 error) {
+	fake.listCommitsMutex.Lock()
+	defer fake.listCommitsMutex.Unlock()
+	fake.ListCommitsStub = nil
+	if fake.listCommitsReturnsOnCall == nil {
+		fake.listCommitsReturnsOnCall = make(map[int]struct {
+			result1 []resource.CommitObject
 			result2 error
 		})
 	}
-	fake.listModifiedFilesReturnsOnCall[i] = struct {
-		result1 []string
+	fake.listCommitsReturnsOnCall[i] = struct {
+		result1 []resource.CommitObject
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) ListPullRequests(arg1 []githubv4.PullRequestState) ([]*resource.PullRequest, error) {
-	var arg1Copy []githubv4.PullRequestState
-	if arg1 != nil {
-		arg1Copy = make([]githubv4.PullRequestState, len(arg1))
-		copy(arg1Copy, arg1)
+func (fake *FakeGithub) ListPullRequests(arg1 context.Context, arg2 []githubv4.PullRequestState, arg3 bool, arg4 time.Time) ([]*resource.PullRequest, error) {
+	var arg2Copy []githubv4.PullRequestState
+	if arg2 != nil {
+		arg2Copy = make([]githubv4.PullRequestState, len(arg2))
+		copy(arg2Copy, arg2)
 	}
 	fake.listPullRequestsMutex.Lock()
 	ret, specificReturn := fake.listPullRequestsReturnsOnCall[len(fake.listPullRequestsArgsForCall)]
 	fake.listPullRequestsArgsForCall = append(fake.listPullRequestsArgsForCall, struct {
-		arg1 []githubv4.PullRequestState
-	}{arg1Copy})
-	fake.recordInvocation("ListPullRequests", []interface{}{arg1Copy})
+		arg1 context.Context
+		arg2 []githubv4.PullRequestState
+		arg3 bool
+		arg4 time.Time
+	}{arg1, arg2Copy, arg3, arg4})
+	fake.recordInvocation("ListPullRequests", []interface{}{arg1, arg2Copy, arg3, arg4})
 	fake.listPullRequestsMutex.Unlock()
 	if fake.ListPullRequestsStub != nil {
-		return fake.ListPullRequestsStub(arg1)
+		return fake.ListPullRequestsStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -386,17 +496,17 @@ func (fake *FakeGithub) ListPullRequestsCallCount() int {
 	return len(fake.listPullRequestsArgsForCall)
 }
 
-func (fake *FakeGithub) ListPullRequestsCalls(stub func([]githubv4.PullRequestState) ([]*resource.PullRequest, error)) {
+func (fake *FakeGithub) ListPullRequestsCalls(stub func(context.Context, []githubv4.PullRequestState, bool, time.Time) ([]*resource.PullRequest, error)) {
 	fake.listPullRequestsMutex.Lock()
 	defer fake.listPullRequestsMutex.Unlock()
 	fake.ListPullRequestsStub = stub
 }
 
-func (fake *FakeGithub) ListPullRequestsArgsForCall(i int) []githubv4.PullRequestState {
+func (fake *FakeGithub) ListPullRequestsArgsForCall(i int) (context.Context, []githubv4.PullRequestState, bool, time.Time) {
 	fake.listPullRequestsMutex.RLock()
 	defer fake.listPullRequestsMutex.RUnlock()
 	argsForCall := fake.listPullRequestsArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeGithub) ListPullRequestsReturns(result1 []*resource.PullRequest, result2 error) {
@@ -425,17 +535,82 @@ func (fake *FakeGithub) ListPullRequestsReturnsOnCall(i int, result1 []*resource
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) PostComment(arg1 string, arg2 string) error {
+func (fake *FakeGithub) ListTimelineItems(arg1 context.Context, arg2 string) ([]resource.TimelineItem, error) {
+	fake.listTimelineItemsMutex.Lock()
+	ret, specificReturn := fake.listTimelineItemsReturnsOnCall[len(fake.listTimelineItemsArgsForCall)]
+	fake.listTimelineItemsArgsForCall = append(fake.listTimelineItemsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ListTimelineItems", []interface{}{arg1, arg2})
+	fake.listTimelineItemsMutex.Unlock()
+	if fake.ListTimelineItemsStub != nil {
+		return fake.ListTimelineItemsStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.listTimelineItemsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGithub) ListTimelineItemsCallCount() int {
+	fake.listTimelineItemsMutex.RLock()
+	defer fake.listTimelineItemsMutex.RUnlock()
+	return len(fake.listTimelineItemsArgsForCall)
+}
+
+func (fake *FakeGithub) ListTimelineItemsCalls(stub func(context.Context, string) ([]resource.TimelineItem, error)) {
+	fake.listTimelineItemsMutex.Lock()
+	defer fake.listTimelineItemsMutex.Unlock()
+	fake.ListTimelineItemsStub = stub
+}
+
+func (fake *FakeGithub) ListTimelineItemsArgsForCall(i int) (context.Context, string) {
+	fake.listTimelineItemsMutex.RLock()
+	defer fake.listTimelineItemsMutex.RUnlock()
+	argsForCall := fake.listTimelineItemsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeGithub) ListTimelineItemsReturns(result1 []resource.TimelineItem, result2 error) {
+	fake.listTimelineItemsMutex.Lock()
+	defer fake.listTimelineItemsMutex.Unlock()
+	fake.ListTimelineItemsStub = nil
+	fake.listTimelineItemsReturns = struct {
+		result1 []resource.TimelineItem
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) ListTimelineItemsReturnsOnCall(i int, result1 []resource.TimelineItem, result2 error) {
+	fake.listTimelineItemsMutex.Lock()
+	defer fake.listTimelineItemsMutex.Unlock()
+	fake.ListTimelineItemsStub = nil
+	if fake.listTimelineItemsReturnsOnCall == nil {
+		fake.listTimelineItemsReturnsOnCall = make(map[int]struct {
+			result1 []resource.TimelineItem
+			result2 error
+		})
+	}
+	fake.listTimelineItemsReturnsOnCall[i] = struct {
+		result1 []resource.TimelineItem
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) PostComment(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.postCommentMutex.Lock()
 	ret, specificReturn := fake.postCommentReturnsOnCall[len(fake.postCommentArgsForCall)]
 	fake.postCommentArgsForCall = append(fake.postCommentArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("PostComment", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("PostComment", []interface{}{arg1, arg2, arg3})
 	fake.postCommentMutex.Unlock()
 	if fake.PostCommentStub != nil {
-		return fake.PostCommentStub(arg1, arg2)
+		return fake.PostCommentStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -450,17 +625,17 @@ func (fake *FakeGithub) PostCommentCallCount() int {
 	return len(fake.postCommentArgsForCall)
 }
 
-func (fake *FakeGithub) PostCommentCalls(stub func(string, string) error) {
+func (fake *FakeGithub) PostCommentCalls(stub func(context.Context, string, string) error) {
 	fake.postCommentMutex.Lock()
 	defer fake.postCommentMutex.Unlock()
 	fake.PostCommentStub = stub
 }
 
-func (fake *FakeGithub) PostCommentArgsForCall(i int) (string, string) {
+func (fake *FakeGithub) PostCommentArgsForCall(i int) (context.Context, string, string) {
 	fake.postCommentMutex.RLock()
 	defer fake.postCommentMutex.RUnlock()
 	argsForCall := fake.postCommentArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGithub) PostCommentReturns(result1 error) {
@@ -486,21 +661,22 @@ func (fake *FakeGithub) PostCommentReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGithub) UpdateCommitStatus(arg1 string, arg2 string, arg3 string, arg4 string, arg5 string, arg6 string) error {
+func (fake *FakeGithub) UpdateCommitStatus(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 string, arg6 string, arg7 string) error {
 	fake.updateCommitStatusMutex.Lock()
 	ret, specificReturn := fake.updateCommitStatusReturnsOnCall[len(fake.updateCommitStatusArgsForCall)]
 	fake.updateCommitStatusArgsForCall = append(fake.updateCommitStatusArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
 		arg5 string
 		arg6 string
-	}{arg1, arg2, arg3, arg4, arg5, arg6})
-	fake.recordInvocation("UpdateCommitStatus", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+		arg7 string
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.recordInvocation("UpdateCommitStatus", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
 	fake.updateCommitStatusMutex.Unlock()
 	if fake.UpdateCommitStatusStub != nil {
-		return fake.UpdateCommitStatusStub(arg1, arg2, arg3, arg4, arg5, arg6)
+		return fake.UpdateCommitStatusStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 	}
 	if specificReturn {
 		return ret.result1
@@ -515,17 +691,17 @@ func (fake *FakeGithub) UpdateCommitStatusCallCount() int {
 	return len(fake.updateCommitStatusArgsForCall)
 }
 
-func (fake *FakeGithub) UpdateCommitStatusCalls(stub func(string, string, string, string, string, string) error) {
+func (fake *FakeGithub) UpdateCommitStatusCalls(stub func(context.Context, string, string, string, string, string, string) error) {
 	fake.updateCommitStatusMutex.Lock()
 	defer fake.updateCommitStatusMutex.Unlock()
 	fake.UpdateCommitStatusStub = stub
 }
 
-func (fake *FakeGithub) UpdateCommitStatusArgsForCall(i int) (string, string, string, string, string, string) {
+func (fake *FakeGithub) UpdateCommitStatusArgsForCall(i int) (context.Context, string, string, string, string, string, string) {
 	fake.updateCommitStatusMutex.RLock()
 	defer fake.updateCommitStatusMutex.RUnlock()
 	argsForCall := fake.updateCommitStatusArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
 }
 
 func (fake *FakeGithub) UpdateCommitStatusReturns(result1 error) {
@@ -558,12 +734,16 @@ func (fake *FakeGithub) Invocations() map[string][][]interface{} {
 	defer fake.deletePreviousCommentsMutex.RUnlock()
 	fake.getChangedFilesMutex.RLock()
 	defer fake.getChangedFilesMutex.RUnlock()
+	fake.getPermissionMutex.RLock()
+	defer fake.getPermissionMutex.RUnlock()
 	fake.getPullRequestMutex.RLock()
 	defer fake.getPullRequestMutex.RUnlock()
-	fake.listModifiedFilesMutex.RLock()
-	defer fake.listModifiedFilesMutex.RUnlock()
+	fake.listCommitsMutex.RLock()
+	defer fake.listCommitsMutex.RUnlock()
 	fake.listPullRequestsMutex.RLock()
 	defer fake.listPullRequestsMutex.RUnlock()
+	fake.listTimelineItemsMutex.RLock()
+	defer fake.listTimelineItemsMutex.RUnlock()
 	fake.postCommentMutex.RLock()
 	defer fake.postCommentMutex.RUnlock()
 	fake.updateCommitStatusMutex.RLock()
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -11,86 +12,194 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
 )
 
 // Git interface for testing purposes.
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/fake_git.go . Git
 type Git interface {
-	Init(string) error
-	Pull(string, string, int, bool, bool) error
-	RevParse(string) (string, error)
-	Fetch(string, int, int, bool) error
-	Checkout(string, string, bool) error
-	Merge(string, bool) error
-	Rebase(string, string, bool) error
-	GitCryptUnlock(string) error
+	Init(context.Context, string) error
+	Pull(context.Context, string, string, int, bool, bool) error
+	RevParse(context.Context, string) (string, error)
+	Reset(context.Context, string, int, bool) error
+	Fetch(context.Context, string, int, int, bool) error
+	Checkout(context.Context, string, string, bool) error
+	Merge(context.Context, string, bool) error
+	Rebase(context.Context, string, string, bool) error
+	GitCryptUnlock(context.Context, string) error
 }
 
 // NewGitClient ...
-func NewGitClient(source *Source, dir string, output io.Writer) (*GitClient, error) {
+func NewGitClient(source *Source, tokenSource oauth2.TokenSource, dir string, output io.Writer) (*GitClient, error) {
 	if source.SkipSSLVerification {
 		os.Setenv("GIT_SSL_NO_VERIFY", "true")
 	}
 	if source.DisableGitLFS {
 		os.Setenv("GIT_LFS_SKIP_SMUDGE", "true")
 	}
+	if _, err := tokenSource.Token(); err != nil {
+		return nil, fmt.Errorf("failed to get access token: %s", err)
+	}
+
+	// Git needs the CA certificates in a file, which replaces its default bundle. The system
+	// bundle is included so that ca_cert is trusted in addition to the system CAs (as for the API).
+	var caCertFile string
+	if source.CACert != "" {
+		f, err := ioutil.TempFile("", "ca-cert")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ca certificate file: %s", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(systemCABundle() + "\n" + source.CACert); err != nil {
+			os.Remove(f.Name())
+			return nil, fmt.Errorf("failed to write ca certificate file: %s", err)
+		}
+		caCertFile = f.Name()
+	}
+
 	return &GitClient{
-		AccessToken: source.AccessToken,
+		TokenSource: tokenSource,
+		CACertFile:  caCertFile,
+		Proxy:       source.Proxy,
+		NoProxy:     source.NoProxy,
+		Timeout:     time.Duration(source.GitTimeout),
 		Directory:   dir,
 		Output:      output,
 	}, nil
 }
 
+// systemCABundles are the locations of the system CA bundle on common distributions
+// (the same as those used by crypto/x509).
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux
+}
+
+// systemCABundle returns the PEM encoded system CA certificates, or an empty string if they are not found.
+func systemCABundle() string {
+	files := systemCABundles
+	if f := os.Getenv("SSL_CERT_FILE"); f != "" {
+		files = append([]string{f}, files...)
+	}
+	for _, f := range files {
+		if b, err := ioutil.ReadFile(f); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
+
 // GitClient ...
 type GitClient struct {
-	AccessToken string
+	TokenSource oauth2.TokenSource
+	CACertFile  string
+	Proxy       string
+	NoProxy     string
+	Timeout     time.Duration
 	Directory   string
 	Output      io.Writer
 }
 
-func (g *GitClient) command(name string, arg ...string) *exec.Cmd {
-	cmd := exec.Command(name, arg...)
+// Close removes the CA certificate file (if any).
+func (g *GitClient) Close() error {
+	if g.CACertFile == "" {
+		return nil
+	}
+	return os.Remove(g.CACertFile)
+}
+
+// withTimeout returns a context which is cancelled after the configured git timeout (if any).
+func (g *GitClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.Timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, g.Timeout)
+}
+
+// accessToken returns the current access token. Tokens are read for each command, so that
+// installation tokens (which expire after an hour) are refreshed for long running gets.
+func (g *GitClient) accessToken() (string, error) {
+	token, err := g.TokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %s", err)
+	}
+	return token.AccessToken, nil
+}
+
+func (g *GitClient) command(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	if name == "git" {
+		var config []string
+		if g.CACertFile != "" {
+			config = append(config, "-c", "http.sslCAInfo="+g.CACertFile)
+		}
+		if g.Proxy != "" {
+			config = append(config, "-c", "http.proxy="+g.Proxy)
+		}
+		arg = append(config, arg...)
+	}
+	LoggerFromContext(ctx).Debug("command", Fields{"name": name, "args": arg, "dir": g.Directory})
+	cmd := exec.CommandContext(ctx, name, arg...)
 	cmd.Dir = g.Directory
 	cmd.Stdout = g.Output
 	cmd.Stderr = g.Output
+	// Git fails to authenticate without a token, so the error is written to the output to explain why.
+	token, err := g.accessToken()
+	if err != nil {
+		fmt.Fprintln(g.Output, err)
+	}
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env,
-		"X_OAUTH_BASIC_TOKEN="+g.AccessToken,
+		"X_OAUTH_BASIC_TOKEN="+token,
 		"GIT_ASKPASS=/usr/local/bin/askpass.sh")
+	if g.NoProxy != "" {
+		cmd.Env = append(cmd.Env, "no_proxy="+g.NoProxy, "NO_PROXY="+g.NoProxy)
+	}
 	return cmd
 }
 
 // Init ...
-func (g *GitClient) Init(branch string) error {
-	if err := g.command("git", "init").Run(); err != nil {
+func (g *GitClient) Init(ctx context.Context, branch string) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
+	if err := g.command(ctx, "git", "init").Run(); err != nil {
 		return fmt.Errorf("init failed: %s", err)
 	}
-	if err := g.command("git", "checkout", "-b", branch).Run(); err != nil {
+	if err := g.command(ctx, "git", "checkout", "-b", branch).Run(); err != nil {
 		return fmt.Errorf("checkout to '%s' failed: %s", branch, err)
 	}
-	if err := g.command("git", "config", "user.name", "concourse-ci").Run(); err != nil {
+	if err := g.command(ctx, "git", "config", "user.name", "concourse-ci").Run(); err != nil {
 		return fmt.Errorf("failed to configure git user: %s", err)
 	}
-	if err := g.command("git", "config", "user.email", "concourse@local").Run(); err != nil {
+	if err := g.command(ctx, "git", "config", "user.email", "concourse@local").Run(); err != nil {
 		return fmt.Errorf("failed to configure git email: %s", err)
 	}
-	if err := g.command("git", "config", "url.https://x-oauth-basic@github.com/.insteadOf", "git@github.com:").Run(); err != nil {
+	if err := g.command(ctx, "git", "config", "url.https://x-oauth-basic@github.com/.insteadOf", "git@github.com:").Run(); err != nil {
 		return fmt.Errorf("failed to configure github url: %s", err)
 	}
-	if err := g.command("git", "config", "url.https://.insteadOf", "git://").Run(); err != nil {
+	if err := g.command(ctx, "git", "config", "url.https://.insteadOf", "git://").Run(); err != nil {
 		return fmt.Errorf("failed to configure github url: %s", err)
 	}
 	return nil
 }
 
 // Pull ...
-func (g *GitClient) Pull(uri, branch string, depth int, submodules bool, fetchTags bool) error {
+func (g *GitClient) Pull(ctx context.Context, uri, branch string, depth int, submodules bool, fetchTags bool) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
 	endpoint, err := g.Endpoint(uri)
 	if err != nil {
 		return err
 	}
 
-	if err := g.command("git", "remote", "add", "origin", endpoint).Run(); err != nil {
+	if err := g.command(ctx, "git", "remote", "add", "origin", endpoint).Run(); err != nil {
 		return fmt.Errorf("setting 'origin' remote to '%s' failed: %s", endpoint, err)
 	}
 
@@ -104,7 +213,7 @@ func (g *GitClient) Pull(uri, branch string, depth int, submodules bool, fetchTa
 	if submodules {
 		args = append(args, "--recurse-submodules")
 	}
-	cmd := g.command("git", args...)
+	cmd := g.command(ctx, "git", args...)
 
 	// Discard output to have zero chance of logging the access token.
 	cmd.Stdout = ioutil.Discard
@@ -114,7 +223,7 @@ func (g *GitClient) Pull(uri, branch string, depth int, submodules bool, fetchTa
 		return fmt.Errorf("pull failed: %s", cmd)
 	}
 	if submodules {
-		submodulesGet := g.command("git", "submodule", "update", "--init", "--recursive")
+		submodulesGet := g.command(ctx, "git", "submodule", "update", "--init", "--recursive")
 		if err := submodulesGet.Run(); err != nil {
 			return fmt.Errorf("submodule update failed: %s", err)
 		}
@@ -123,8 +232,12 @@ func (g *GitClient) Pull(uri, branch string, depth int, submodules bool, fetchTa
 }
 
 // RevParse retrieves the SHA of the given branch.
-func (g *GitClient) RevParse(branch string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--verify", branch)
+func (g *GitClient) RevParse(ctx context.Context, branch string) (string, error) {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
+	LoggerFromContext(ctx).Debug("command", Fields{"name": "git", "args": []string{"rev-parse", "--verify", branch}, "dir": g.Directory})
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", branch)
 	cmd.Dir = g.Directory
 	sha, err := cmd.CombinedOutput()
 	if err != nil {
@@ -133,8 +246,45 @@ func (g *GitClient) RevParse(branch string) (string, error) {
 	return strings.TrimSpace(string(sha)), nil
 }
 
+// Reset the current branch to the given commit, which is fetched from origin
+// if it was not pulled (e.g. due to the depth).
+func (g *GitClient) Reset(ctx context.Context, sha string, depth int, submodules bool) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
+	if err := g.command(ctx, "git", "cat-file", "-e", sha+"^{commit}").Run(); err != nil {
+		args := []string{"fetch", "origin", sha}
+		if depth > 0 {
+			args = append(args, "--depth", strconv.Itoa(depth))
+		}
+		cmd := g.command(ctx, "git", args...)
+
+		// Discard output to have zero chance of logging the access token.
+		cmd.Stdout = ioutil.Discard
+		cmd.Stderr = ioutil.Discard
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("fetch of '%s' failed: %s", sha, err)
+		}
+	}
+
+	if err := g.command(ctx, "git", "reset", "--hard", sha).Run(); err != nil {
+		return fmt.Errorf("reset failed: %s", err)
+	}
+
+	if submodules {
+		if err := g.command(ctx, "git", "submodule", "update", "--init", "--recursive").Run(); err != nil {
+			return fmt.Errorf("submodule update failed: %s", err)
+		}
+	}
+	return nil
+}
+
 // Fetch ...
-func (g *GitClient) Fetch(uri string, prNumber int, depth int, submodules bool) error {
+func (g *GitClient) Fetch(ctx context.Context, uri string, prNumber int, depth int, submodules bool) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
 	endpoint, err := g.Endpoint(uri)
 	if err != nil {
 		return err
@@ -147,7 +297,7 @@ func (g *GitClient) Fetch(uri string, prNumber int, depth int, submodules bool)
 	if submodules {
 		args = append(args, "--recurse-submodules")
 	}
-	cmd := g.command("git", args...)
+	cmd := g.command(ctx, "git", args...)
 
 	// Discard output to have zero chance of logging the access token.
 	cmd.Stdout = ioutil.Discard
@@ -160,13 +310,16 @@ func (g *GitClient) Fetch(uri string, prNumber int, depth int, submodules bool)
 }
 
 // CheckOut
-func (g *GitClient) Checkout(branch, sha string, submodules bool) error {
-	if err := g.command("git", "checkout", "-b", branch, sha).Run(); err != nil {
+func (g *GitClient) Checkout(ctx context.Context, branch, sha string, submodules bool) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
+	if err := g.command(ctx, "git", "checkout", "-b", branch, sha).Run(); err != nil {
 		return fmt.Errorf("checkout failed: %s", err)
 	}
 
 	if submodules {
-		if err := g.command("git", "submodule", "update", "--init", "--recursive", "--checkout").Run(); err != nil {
+		if err := g.command(ctx, "git", "submodule", "update", "--init", "--recursive", "--checkout").Run(); err != nil {
 			return fmt.Errorf("submodule update failed: %s", err)
 		}
 	}
@@ -175,13 +328,16 @@ func (g *GitClient) Checkout(branch, sha string, submodules bool) error {
 }
 
 // Merge ...
-func (g *GitClient) Merge(sha string, submodules bool) error {
-	if err := g.command("git", "merge", sha, "--no-stat").Run(); err != nil {
+func (g *GitClient) Merge(ctx context.Context, sha string, submodules bool) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
+	if err := g.command(ctx, "git", "merge", sha, "--no-stat").Run(); err != nil {
 		return fmt.Errorf("merge failed: %s", err)
 	}
 
 	if submodules {
-		if err := g.command("git", "submodule", "update", "--init", "--recursive", "--merge").Run(); err != nil {
+		if err := g.command(ctx, "git", "submodule", "update", "--init", "--recursive", "--merge").Run(); err != nil {
 			return fmt.Errorf("submodule update failed: %s", err)
 		}
 	}
@@ -190,13 +346,16 @@ func (g *GitClient) Merge(sha string, submodules bool) error {
 }
 
 // Rebase ...
-func (g *GitClient) Rebase(baseRef string, headSha string, submodules bool) error {
-	if err := g.command("git", "rebase", baseRef, headSha).Run(); err != nil {
+func (g *GitClient) Rebase(ctx context.Context, baseRef string, headSha string, submodules bool) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
+	if err := g.command(ctx, "git", "rebase", baseRef, headSha).Run(); err != nil {
 		return fmt.Errorf("rebase failed: %s", err)
 	}
 
 	if submodules {
-		if err := g.command("git", "submodule", "update", "--init", "--recursive", "--rebase").Run(); err != nil {
+		if err := g.command(ctx, "git", "submodule", "update", "--init", "--recursive", "--rebase").Run(); err != nil {
 			return fmt.Errorf("submodule update failed: %s", err)
 		}
 	}
@@ -205,7 +364,10 @@ func (g *GitClient) Rebase(baseRef string, headSha string, submodules bool) erro
 }
 
 // GitCryptUnlock unlocks the repository using git-crypt
-func (g *GitClient) GitCryptUnlock(base64key string) error {
+func (g *GitClient) GitCryptUnlock(ctx context.Context, base64key string) error {
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+
 	keyDir, err := ioutil.TempDir("", "")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary directory")
@@ -219,7 +381,7 @@ func (g *GitClient) GitCryptUnlock(base64key string) error {
 	if err := ioutil.WriteFile(keyPath, decodedKey, os.FileMode(0600)); err != nil {
 		return fmt.Errorf("failed to write git-crypt key to file: %s", err)
 	}
-	if err := g.command("git-crypt", "unlock", keyPath).Run(); err != nil {
+	if err := g.command(ctx, "git-crypt", "unlock", keyPath).Run(); err != nil {
 		return fmt.Errorf("git-crypt unlock failed: %s", err)
 	}
 	return nil
@@ -231,6 +393,10 @@ func (g *GitClient) Endpoint(uri string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to parse commit url: %s", err)
 	}
-	endpoint.User = url.UserPassword("x-oauth-basic", g.AccessToken)
+	token, err := g.accessToken()
+	if err != nil {
+		return "", err
+	}
+	endpoint.User = url.UserPassword("x-oauth-basic", token)
 	return endpoint.String(), nil
 }
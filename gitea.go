@@ -0,0 +1,659 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+)
+
+// giteaPageSize is the number of items requested per page, which is capped by
+// the MAX_RESPONSE_ITEMS setting of the Gitea instance (50 by default).
+const giteaPageSize = 50
+
+// GiteaClient for handling requests to the Gitea (or Forgejo) REST API.
+type GiteaClient struct {
+	Client      *http.Client
+	Endpoint    string
+	TokenSource oauth2.TokenSource
+	Repository  string
+	Owner       string
+
+	// Each of these takes a request per pull request, so they are only listed when the source needs them.
+	ListStatuses    bool
+	ListTriggers    bool
+	ListBaseCommits bool
+}
+
+// NewGiteaClient ...
+func NewGiteaClient(s *Source) (*GiteaClient, error) {
+	owner, repository, err := parseRepository(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := url.Parse(s.V3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse v3 endpoint: %s", err)
+	}
+
+	transport, err := newTransport(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaClient{
+		Client: &http.Client{
+			Transport: newRetryTransport(transport, s.MaxRetries, time.Duration(s.MaxRetryWait), time.Duration(s.RequestTimeout)),
+		},
+		Endpoint:        strings.TrimSuffix(endpoint.String(), "/"),
+		TokenSource:     oauth2.StaticTokenSource(&oauth2.Token{AccessToken: s.AccessToken}),
+		Owner:           owner,
+		Repository:      repository,
+		ListStatuses:    len(s.RequiredStatuses) > 0,
+		ListTriggers:    len(s.TriggerLabels) > 0 || s.CommentTrigger != "",
+		ListBaseCommits: s.TriggerOnBaseChange,
+	}, nil
+}
+
+// giteaPullRequest represents a pull request in the Gitea API.
+type giteaPullRequest struct {
+	ID        int64         `json:"id"`
+	Number    int           `json:"number"`
+	Title     string        `json:"title"`
+	Body      string        `json:"body"`
+	HTMLURL   string        `json:"html_url"`
+	State     string        `json:"state"`
+	Draft     bool          `json:"draft"`
+	Merged    bool          `json:"merged"`
+	Mergeable bool          `json:"mergeable"`
+	MergedAt  *time.Time    `json:"merged_at"`
+	ClosedAt  *time.Time    `json:"closed_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Labels    []LabelObject `json:"labels"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Base giteaBranchInfo `json:"base"`
+	Head giteaBranchInfo `json:"head"`
+}
+
+// giteaBranchInfo represents the base or head of a pull request in the Gitea API.
+type giteaBranchInfo struct {
+	Ref    string `json:"ref"`
+	Sha    string `json:"sha"`
+	RepoID int64  `json:"repo_id"`
+	Repo   struct {
+		CloneURL string `json:"clone_url"`
+	} `json:"repo"`
+}
+
+// giteaCommit represents a commit in the Gitea API.
+type giteaCommit struct {
+	Sha    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Email string `json:"email"`
+		} `json:"author"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+// giteaComment represents an issue comment in the Gitea API.
+type giteaComment struct {
+	ID   int64 `json:"id"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// ListPullRequests gets the last commit on all pull requests with the matching state,
+// and optionally the files changed in each pull request. Pull requests are listed
+// by when they were last updated, and listing stops at the first pull request
+// which has not been updated since the given time (unless it is zero).
+func (m *GiteaClient) ListPullRequests(ctx context.Context, prStates []githubv4.PullRequestState, includeFiles bool, since time.Time) ([]*PullRequest, error) {
+	wanted := make(map[githubv4.PullRequestState]bool, len(prStates))
+	for _, state := range prStates {
+		wanted[state] = true
+	}
+
+	// Gitea only distinguishes between open and closed (which includes merged) pull requests.
+	state := "all"
+	if !wanted[githubv4.PullRequestStateOpen] {
+		state = "closed"
+	} else if !wanted[githubv4.PullRequestStateClosed] && !wanted[githubv4.PullRequestStateMerged] {
+		state = "open"
+	}
+
+	var response []*PullRequest
+	var pages int
+	defer func() {
+		LoggerFromContext(ctx).Debug("listed pull requests", Fields{"pages": pages, "pull_requests": len(response)})
+	}()
+
+	// Most pull requests share the same base, so the base commits are cached.
+	bases := make(map[string]giteaCommit)
+	for page := 1; ; page++ {
+		var prs []giteaPullRequest
+		query := url.Values{
+			"state": {state},
+			"sort":  {"recentupdate"},
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(giteaPageSize)},
+		}
+		if err := m.do(ctx, http.MethodGet, m.repoPath("pulls"), query, nil, &prs); err != nil {
+			return nil, err
+		}
+		pages++
+
+		for _, p := range prs {
+			if !since.IsZero() && !p.UpdatedAt.After(since) {
+				return response, nil
+			}
+			if !wanted[giteaPullRequestState(p)] {
+				continue
+			}
+
+			var tip giteaCommit
+			if err := m.do(ctx, http.MethodGet, m.repoPath("git", "commits", p.Head.Sha), nil, nil, &tip); err != nil {
+				return nil, err
+			}
+			pr, err := m.newPullRequest(ctx, p, tip)
+			if err != nil {
+				return nil, err
+			}
+			if includeFiles {
+				if pr.Files, err = m.GetChangedFiles(ctx, strconv.Itoa(p.Number), p.Head.Sha); err != nil {
+					return nil, err
+				}
+			}
+			if m.ListStatuses {
+				if pr.Statuses, err = m.listStatuses(ctx, p.Head.Sha); err != nil {
+					return nil, err
+				}
+			}
+			// The timeline has the push dates of open pull requests, which date their versions.
+			if m.ListTriggers || pr.State == githubv4.PullRequestStateOpen {
+				if pr.TimelineItems, err = m.listTimelineItems(ctx, strconv.Itoa(p.Number), since); err != nil {
+					return nil, err
+				}
+			}
+
+			pr.BaseRef.Target.OID = p.Base.Sha
+			if m.ListBaseCommits && p.Base.Sha != "" {
+				base, ok := bases[p.Base.Sha]
+				if !ok {
+					if err := m.do(ctx, http.MethodGet, m.repoPath("git", "commits", p.Base.Sha), nil, nil, &base); err != nil {
+						return nil, err
+					}
+					bases[p.Base.Sha] = base
+				}
+				pr.BaseRef.Target.Commit.CommittedDate = githubv4.DateTime{Time: base.Commit.Committer.Date}
+			}
+			response = append(response, pr)
+		}
+		if len(prs) < giteaPageSize {
+			return response, nil
+		}
+	}
+}
+
+// listStatuses returns the latest status of each context for a commit.
+func (m *GiteaClient) listStatuses(ctx context.Context, sha string) ([]StatusObject, error) {
+	var combined struct {
+		Statuses []struct {
+			Context   string    `json:"context"`
+			Status    string    `json:"status"`
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"statuses"`
+	}
+	if err := m.do(ctx, http.MethodGet, m.repoPath("commits", sha, "status"), nil, nil, &combined); err != nil {
+		return nil, err
+	}
+	var statuses []StatusObject
+	for _, s := range combined.Statuses {
+		statuses = append(statuses, StatusObject{Name: s.Context, State: strings.ToUpper(s.Status), CompletedAt: s.UpdatedAt})
+	}
+	return statuses, nil
+}
+
+// PostComment to a pull request or issue.
+func (m *GiteaClient) PostComment(ctx context.Context, prNumber, comment string) error {
+	body := map[string]string{"body": comment}
+	return m.do(ctx, http.MethodPost, m.repoPath("issues", prNumber, "comments"), nil, body, nil)
+}
+
+// GetChangedFiles returns the list of files changed in a pull request.
+func (m *GiteaClient) GetChangedFiles(ctx context.Context, prNumber string, commitRef string) ([]ChangedFileObject, error) {
+	var files []ChangedFileObject
+	for page := 1; ; page++ {
+		var result []struct {
+			Filename string `json:"filename"`
+		}
+		query := url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(giteaPageSize)},
+		}
+		if err := m.do(ctx, http.MethodGet, m.repoPath("pulls", prNumber, "files"), query, nil, &result); err != nil {
+			return nil, err
+		}
+		for _, f := range result {
+			files = append(files, ChangedFileObject{Path: f.Filename})
+		}
+		if len(result) < giteaPageSize {
+			return files, nil
+		}
+	}
+}
+
+// GetPullRequest ...
+func (m *GiteaClient) GetPullRequest(ctx context.Context, prNumber, commitRef string) (*PullRequest, error) {
+	var pr giteaPullRequest
+	if err := m.do(ctx, http.MethodGet, m.repoPath("pulls", prNumber), nil, nil, &pr); err != nil {
+		return nil, err
+	}
+
+	for page := 1; ; page++ {
+		var commits []giteaCommit
+		query := url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(giteaPageSize)},
+		}
+		if err := m.do(ctx, http.MethodGet, m.repoPath("pulls", prNumber, "commits"), query, nil, &commits); err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			if c.Sha == commitRef {
+				return m.newPullRequest(ctx, pr, c)
+			}
+		}
+		if len(commits) < giteaPageSize {
+			break
+		}
+	}
+	return nil, fmt.Errorf("commit with ref '%s' does not exist", commitRef)
+}
+
+// ListCommits returns the commits of a pull request, oldest first.
+func (m *GiteaClient) ListCommits(ctx context.Context, prNumber string) ([]CommitObject, error) {
+	var response []CommitObject
+	for page := 1; ; page++ {
+		var commits []giteaCommit
+		query := url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(giteaPageSize)},
+		}
+		if err := m.do(ctx, http.MethodGet, m.repoPath("pulls", prNumber, "commits"), query, nil, &commits); err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			response = append(response, c.commitObject())
+		}
+		if len(commits) < giteaPageSize {
+			return response, nil
+		}
+	}
+}
+
+// UpdateCommitStatus for a given commit.
+func (m *GiteaClient) UpdateCommitStatus(ctx context.Context, commitRef, baseContext, statusContext, status, targetURL, description string) error {
+	statusContext, targetURL, description = statusDefaults(baseContext, statusContext, status, targetURL, description)
+
+	body := map[string]string{
+		"state":       strings.ToLower(status),
+		"target_url":  targetURL,
+		"description": description,
+		"context":     statusContext,
+	}
+	return m.do(ctx, http.MethodPost, m.repoPath("statuses", commitRef), nil, body, nil)
+}
+
+// DeletePreviousComments made by the authenticated user on a pull request.
+func (m *GiteaClient) DeletePreviousComments(ctx context.Context, prNumber string) error {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := m.do(ctx, http.MethodGet, "/user", nil, nil, &user); err != nil {
+		return err
+	}
+
+	var comments []giteaComment
+	if err := m.do(ctx, http.MethodGet, m.repoPath("issues", prNumber, "comments"), nil, nil, &comments); err != nil {
+		return err
+	}
+
+	for _, c := range comments {
+		if c.User.Login != user.Login {
+			continue
+		}
+		if err := m.do(ctx, http.MethodDelete, m.repoPath("issues", "comments", strconv.FormatInt(c.ID, 10)), nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// giteaTimelineComment represents an item on the timeline of an issue in the Gitea API.
+type giteaTimelineComment struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Label *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+}
+
+// ListTimelineItems returns the labels, comments and pushes on the timeline of a pull request, in order.
+func (m *GiteaClient) ListTimelineItems(ctx context.Context, prNumber string) ([]TimelineItem, error) {
+	return m.listTimelineItems(ctx, prNumber, time.Time{})
+}
+
+// listTimelineItems returns the timeline of a pull request, starting at since (if set).
+func (m *GiteaClient) listTimelineItems(ctx context.Context, prNumber string, since time.Time) ([]TimelineItem, error) {
+	var items []TimelineItem
+	for page := 1; ; page++ {
+		var comments []giteaTimelineComment
+		query := url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(giteaPageSize)},
+		}
+		if !since.IsZero() {
+			query.Set("since", since.Format(time.RFC3339))
+		}
+		if err := m.do(ctx, http.MethodGet, m.repoPath("issues", prNumber, "timeline"), query, nil, &comments); err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			items = append(items, giteaTimelineItems(c)...)
+		}
+		if len(comments) < giteaPageSize {
+			return items, nil
+		}
+	}
+}
+
+// giteaTimelineItems converts a Gitea timeline comment to its Github equivalent(s).
+func giteaTimelineItems(c giteaTimelineComment) []TimelineItem {
+	switch c.Type {
+	case "comment":
+		return []TimelineItem{{
+			Type:      githubv4.PullRequestTimelineItemsItemTypeIssueComment,
+			CreatedAt: c.CreatedAt,
+			Actor:     c.User.Login,
+			Body:      c.Body,
+			CommentID: c.ID,
+		}}
+	case "label":
+		// Labels which were added have a body of "1", and removed labels an empty body.
+		if c.Label == nil || c.Body != "1" {
+			return nil
+		}
+		return []TimelineItem{{
+			Type:      githubv4.PullRequestTimelineItemsItemTypeLabeledEvent,
+			CreatedAt: c.CreatedAt,
+			Actor:     c.User.Login,
+			Label:     c.Label.Name,
+		}}
+	case "pull_push":
+		var push struct {
+			IsForcePush bool     `json:"is_force_push"`
+			CommitIDs   []string `json:"commit_ids"`
+		}
+		if err := json.Unmarshal([]byte(c.Body), &push); err != nil {
+			return nil
+		}
+		// Force pushes list the old and new head, while other pushes list the added commits.
+		if push.IsForcePush {
+			if len(push.CommitIDs) != 2 {
+				return nil
+			}
+			return []TimelineItem{{
+				Type:      githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent,
+				CreatedAt: c.CreatedAt,
+				Actor:     c.User.Login,
+				Commit:    push.CommitIDs[1],
+			}}
+		}
+		var items []TimelineItem
+		for _, id := range push.CommitIDs {
+			items = append(items, TimelineItem{
+				Type:      githubv4.PullRequestTimelineItemsItemTypePullRequestCommit,
+				CreatedAt: c.CreatedAt,
+				Actor:     c.User.Login,
+				Commit:    id,
+			})
+		}
+		return items
+	}
+	return nil
+}
+
+// GetPermission returns the permission a user has on the repository (owner, admin, write, read or none).
+func (m *GiteaClient) GetPermission(ctx context.Context, login string) (string, error) {
+	var permission struct {
+		Permission string `json:"permission"`
+	}
+	err := m.do(ctx, http.MethodGet, m.repoPath("collaborators", login, "permission"), nil, nil, &permission)
+	if e, ok := err.(*giteaError); ok && (e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusNotFound) {
+		return "none", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return permission.Permission, nil
+}
+
+// listReviews returns the approving and change requesting reviews of a pull request
+// which have not been dismissed, oldest first.
+func (m *GiteaClient) listReviews(ctx context.Context, prNumber string) ([]ReviewObject, error) {
+	var reviews []ReviewObject
+	for page := 1; ; page++ {
+		var response []struct {
+			User struct {
+				Login string `json:"login"`
+			} `json:"user"`
+			State     string `json:"state"`
+			CommitID  string `json:"commit_id"`
+			Dismissed bool   `json:"dismissed"`
+		}
+		query := url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(giteaPageSize)},
+		}
+		if err := m.do(ctx, http.MethodGet, m.repoPath("pulls", prNumber, "reviews"), query, nil, &response); err != nil {
+			return nil, err
+		}
+		for _, r := range response {
+			var review ReviewObject
+			switch {
+			case r.Dismissed:
+				continue
+			case r.State == "APPROVED":
+				review.State = githubv4.PullRequestReviewStateApproved
+			case r.State == "REQUEST_CHANGES":
+				review.State = githubv4.PullRequestReviewStateChangesRequested
+			default:
+				continue
+			}
+			review.Author.Login = r.User.Login
+			review.Commit.OID = r.CommitID
+			reviews = append(reviews, review)
+		}
+		if len(response) < giteaPageSize {
+			return reviews, nil
+		}
+	}
+}
+
+// newPullRequest converts a Gitea pull request and its tip to a PullRequest,
+// fetching its reviews.
+func (m *GiteaClient) newPullRequest(ctx context.Context, p giteaPullRequest, tip giteaCommit) (*PullRequest, error) {
+	reviews, err := m.listReviews(ctx, strconv.Itoa(p.Number))
+	if err != nil {
+		return nil, err
+	}
+	approved, changesRequested := countReviews(reviews, tip.Sha)
+
+	// Gitea does not expose a review decision, so it is derived from the reviews.
+	decision := githubv4.PullRequestReviewDecisionReviewRequired
+	if changesRequested > 0 {
+		decision = githubv4.PullRequestReviewDecisionChangesRequested
+	} else if approved > 0 {
+		decision = githubv4.PullRequestReviewDecisionApproved
+	}
+
+	pr := &PullRequest{
+		PullRequestObject: PullRequestObject{
+			ID:                strconv.FormatInt(p.ID, 10),
+			Number:            p.Number,
+			Title:             p.Title,
+			Body:              p.Body,
+			URL:               p.HTMLURL,
+			BaseRefName:       p.Base.Ref,
+			HeadRefName:       p.Head.Ref,
+			IsCrossRepository: p.Head.RepoID != p.Base.RepoID,
+			IsDraft:           p.Draft,
+			ReviewDecision:    decision,
+			State:             giteaPullRequestState(p),
+			UpdatedAt:         githubv4.DateTime{Time: p.UpdatedAt},
+		},
+		Tip:                   tip.commitObject(),
+		ApprovedReviewCount:   approved,
+		ChangesRequestedCount: changesRequested,
+		Labels:                p.Labels,
+	}
+	if pr.State == githubv4.PullRequestStateOpen {
+		pr.Mergeable = githubv4.MergeableStateConflicting
+		if p.Mergeable {
+			pr.Mergeable = githubv4.MergeableStateMergeable
+		}
+	}
+	pr.Repository.URL = p.Base.Repo.CloneURL
+	pr.Author.Login = p.User.Login
+	if p.ClosedAt != nil {
+		pr.ClosedAt = githubv4.DateTime{Time: *p.ClosedAt}
+	}
+	if p.MergedAt != nil {
+		pr.MergedAt = githubv4.DateTime{Time: *p.MergedAt}
+	}
+	return pr, nil
+}
+
+// commitObject converts a Gitea commit to a CommitObject.
+func (c giteaCommit) commitObject() CommitObject {
+	commit := CommitObject{
+		ID:            c.Sha,
+		OID:           c.Sha,
+		CommittedDate: githubv4.DateTime{Time: c.Commit.Committer.Date},
+		Message:       c.Commit.Message,
+	}
+	if c.Author != nil {
+		commit.Author.User.Login = c.Author.Login
+	}
+	commit.Author.Email = c.Commit.Author.Email
+	return commit
+}
+
+// giteaPullRequestState maps the state of a Gitea pull request to its Github equivalent.
+func giteaPullRequestState(p giteaPullRequest) githubv4.PullRequestState {
+	switch {
+	case p.Merged:
+		return githubv4.PullRequestStateMerged
+	case p.State == "closed":
+		return githubv4.PullRequestStateClosed
+	default:
+		return githubv4.PullRequestStateOpen
+	}
+}
+
+// repoPath returns the API path for the given elements under the repository.
+func (m *GiteaClient) repoPath(elem ...string) string {
+	parts := []string{"repos", url.PathEscape(m.Owner), url.PathEscape(m.Repository)}
+	for _, e := range elem {
+		parts = append(parts, url.PathEscape(e))
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// do sends a request to the Gitea API, encoding the body (if any) and decoding
+// the response into out (if not nil).
+func (m *GiteaClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := m.Endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %s", err)
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, payload)
+	if err != nil {
+		return err
+	}
+	token, err := m.TokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get token: %s", err)
+	}
+	req.Header.Set("Authorization", "token "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &giteaError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("%s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(message))),
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %s", err)
+	}
+	return nil
+}
+
+// giteaError is returned for unsuccessful responses from the Gitea API.
+type giteaError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *giteaError) Error() string {
+	return e.Message
+}
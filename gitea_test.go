@@ -0,0 +1,379 @@
+package resource_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+// newGiteaServer returns a server which responds with the JSON for the given
+// request method and path (followed by ?page=N for pages after the first), and
+// records the requests it receives.
+func newGiteaServer(t *testing.T, responses map[string]string) (*httptest.Server, *[]string) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token oauthtoken", r.Header.Get("Authorization"))
+
+		key := r.Method + " " + r.URL.Path
+		requests = append(requests, key)
+		if r.Method == http.MethodPost {
+			var body map[string]string
+			if assert.NoError(t, json.NewDecoder(r.Body).Decode(&body)) {
+				b, _ := json.Marshal(body)
+				requests[len(requests)-1] += " " + string(b)
+			}
+		}
+
+		response, ok := responses[key]
+		if page := r.URL.Query().Get("page"); page != "" && page != "1" {
+			response, ok = responses[key+"?page="+page]
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	return server, &requests
+}
+
+func newGiteaClient(t *testing.T, server *httptest.Server) *resource.GiteaClient {
+	source := &resource.Source{
+		Forge:       resource.ForgeGitea,
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL + "/api/v1",
+		MaxRetries:  -1,
+	}
+	require.NoError(t, source.Validate())
+
+	client, err := resource.NewGiteaClient(source)
+	require.NoError(t, err)
+	return client
+}
+
+const (
+	giteaPullRequests = `[
+  {
+    "id": 11,
+    "number": 2,
+    "title": "second",
+    "html_url": "https://gitea.example.com/itsdalmo/test-repository/pulls/2",
+    "state": "closed",
+    "merged": true,
+    "merged_at": "2020-01-03T00:00:00Z",
+    "closed_at": "2020-01-03T00:00:00Z",
+    "updated_at": "2020-01-03T00:00:00Z",
+    "labels": [{"name": "enhancement"}],
+    "base": {"ref": "master", "repo_id": 1, "repo": {"clone_url": "https://gitea.example.com/itsdalmo/test-repository.git"}},
+    "head": {"ref": "feature", "sha": "sha2", "repo_id": 1, "repo": {"clone_url": "https://gitea.example.com/itsdalmo/test-repository.git"}}
+  },
+  {
+    "id": 10,
+    "number": 1,
+    "title": "first",
+    "user": {"login": "someone"},
+    "html_url": "https://gitea.example.com/itsdalmo/test-repository/pulls/1",
+    "state": "open",
+    "mergeable": true,
+    "updated_at": "2020-01-02T00:00:00Z",
+    "base": {"ref": "master", "sha": "base1", "repo_id": 1, "repo": {"clone_url": "https://gitea.example.com/itsdalmo/test-repository.git"}},
+    "head": {"ref": "fork", "sha": "sha1", "repo_id": 2, "repo": {"clone_url": "https://gitea.example.com/someone/test-repository.git"}}
+  }
+]`
+
+	giteaCommit1 = `{
+  "sha": "sha1",
+  "commit": {"message": "commit 1", "author": {"email": "user@example.com"}, "committer": {"date": "2020-01-01T00:00:00Z"}},
+  "author": {"login": "user"}
+}`
+)
+
+func TestGiteaListPullRequests(t *testing.T) {
+	server, requests := newGiteaServer(t, map[string]string{
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls":             giteaPullRequests,
+		"GET /api/v1/repos/itsdalmo/test-repository/git/commits/sha1":  giteaCommit1,
+		"GET /api/v1/repos/itsdalmo/test-repository/git/commits/base1": `{"sha": "base1", "commit": {"committer": {"date": "2020-01-02T00:00:00Z"}}}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/reviews": `[
+			{"user": {"login": "a"}, "state": "APPROVED", "commit_id": "sha1"},
+			{"user": {"login": "a"}, "state": "APPROVED", "commit_id": "sha1"},
+			{"user": {"login": "b"}, "state": "APPROVED", "commit_id": "sha1", "dismissed": true},
+			{"user": {"login": "c"}, "state": "APPROVED", "commit_id": "sha0"},
+			{"user": {"login": "d"}, "state": "REQUEST_CHANGES", "commit_id": "sha0"},
+			{"user": {"login": "e"}, "state": "COMMENT", "commit_id": "sha1"}
+		]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/files":       `[{"filename": "README.md"}, {"filename": "terraform/main.tf"}]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/commits/sha1/status": `{"statuses": [{"context": "lint", "status": "success", "updated_at": "2020-01-04T00:00:00Z"}, {"context": "test", "status": "pending", "updated_at": "2020-01-04T01:00:00Z"}]}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/commits/sha2/status": `{"statuses": []}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/issues/1/timeline":   `[{"id": 5, "type": "comment", "body": "/retest", "user": {"login": "maintainer"}, "created_at": "2020-01-02T00:00:00Z"}]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/issues/2/timeline":   `[]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/git/commits/sha2":    `{"sha": "sha2"}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/2/reviews":     `[]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/2/files":       `[]`,
+	})
+	defer server.Close()
+	client := newGiteaClient(t, server)
+	client.ListStatuses = true
+	client.ListBaseCommits = true
+
+	t.Run("lists open pull requests", func(t *testing.T) {
+		prs, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, true, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, prs, 1)
+
+		pr := prs[0]
+		assert.Equal(t, 1, pr.Number)
+		assert.Equal(t, "first", pr.Title)
+		assert.Equal(t, "https://gitea.example.com/itsdalmo/test-repository/pulls/1", pr.URL)
+		assert.Equal(t, "https://gitea.example.com/itsdalmo/test-repository.git", pr.Repository.URL)
+		assert.Equal(t, "master", pr.BaseRefName)
+		assert.Equal(t, "fork", pr.HeadRefName)
+		assert.Equal(t, "someone", pr.Author.Login)
+		assert.True(t, pr.IsCrossRepository)
+		assert.Equal(t, githubv4.PullRequestStateOpen, pr.State)
+		assert.Equal(t, githubv4.MergeableStateMergeable, pr.Mergeable)
+		assert.Equal(t, "base1", pr.BaseRef.Target.OID)
+		assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), pr.BaseRef.Target.Commit.CommittedDate.Time)
+		assert.Equal(t, "sha1", pr.Tip.OID)
+		assert.Equal(t, "commit 1", pr.Tip.Message)
+		assert.Equal(t, "user", pr.Tip.Author.User.Login)
+		assert.Equal(t, "user@example.com", pr.Tip.Author.Email)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), pr.Tip.CommittedDate.Time)
+		assert.Equal(t, 1, pr.ApprovedReviewCount)
+		assert.Equal(t, 1, pr.ChangesRequestedCount)
+		assert.Equal(t, []resource.StatusObject{
+			{Name: "lint", State: "SUCCESS", CompletedAt: time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC)},
+			{Name: "test", State: "PENDING", CompletedAt: time.Date(2020, 1, 4, 1, 0, 0, 0, time.UTC)},
+		}, pr.Statuses)
+		if assert.Len(t, pr.TimelineItems, 1) {
+			assert.Equal(t, int64(5), pr.TimelineItems[0].CommentID)
+			assert.Equal(t, "/retest", pr.TimelineItems[0].Body)
+		}
+		assert.Equal(t, githubv4.PullRequestReviewDecisionChangesRequested, pr.ReviewDecision)
+		assert.Equal(t, []resource.ChangedFileObject{{Path: "README.md"}, {Path: "terraform/main.tf"}}, pr.Files)
+		assert.Contains(t, *requests, "GET /api/v1/repos/itsdalmo/test-repository/pulls")
+	})
+
+	t.Run("maps merged pull requests", func(t *testing.T) {
+		prs, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateMerged}, false, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, prs, 1)
+
+		pr := prs[0]
+		assert.Equal(t, 2, pr.Number)
+		assert.Equal(t, githubv4.PullRequestStateMerged, pr.State)
+		assert.Equal(t, []resource.LabelObject{{Name: "enhancement"}}, pr.Labels)
+		assert.False(t, pr.IsCrossRepository)
+		assert.Equal(t, time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC), pr.UpdatedDate().Time)
+		assert.Nil(t, pr.Files)
+	})
+
+	t.Run("only lists statuses and base commits when the source needs them", func(t *testing.T) {
+		*requests = nil
+		client := newGiteaClient(t, server)
+		prs, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, prs, 1)
+		assert.Nil(t, prs[0].Statuses)
+		assert.Equal(t, "base1", prs[0].BaseRef.Target.OID)
+		assert.NotContains(t, *requests, "GET /api/v1/repos/itsdalmo/test-repository/commits/sha1/status")
+		assert.NotContains(t, *requests, "GET /api/v1/repos/itsdalmo/test-repository/git/commits/base1")
+	})
+
+	t.Run("stops at pull requests which have not been updated", func(t *testing.T) {
+		states := []githubv4.PullRequestState{githubv4.PullRequestStateOpen, githubv4.PullRequestStateMerged}
+		prs, err := client.ListPullRequests(context.TODO(), states, false, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.Len(t, prs, 1)
+		assert.Equal(t, 2, prs[0].Number)
+	})
+}
+
+func TestGiteaGetPullRequest(t *testing.T) {
+	server, _ := newGiteaServer(t, map[string]string{
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1": `{
+  "id": 10,
+  "number": 1,
+  "state": "open",
+  "base": {"ref": "master", "repo_id": 1},
+  "head": {"ref": "feature", "sha": "sha1", "repo_id": 1}
+}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/commits": `[` + giteaCommit1 + `]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/reviews": `[]`,
+	})
+	defer server.Close()
+	client := newGiteaClient(t, server)
+
+	pr, err := client.GetPullRequest(context.TODO(), "1", "sha1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, pr.Number)
+	assert.Equal(t, "sha1", pr.Tip.OID)
+	assert.Equal(t, "commit 1", pr.Tip.Message)
+
+	_, err = client.GetPullRequest(context.TODO(), "1", "missing")
+	assert.EqualError(t, err, "commit with ref 'missing' does not exist")
+}
+
+func TestGiteaReviewPages(t *testing.T) {
+	// The latest review of each reviewer counts, and Gitea lists reviews oldest first.
+	var reviews []string
+	for i := 0; i < 50; i++ {
+		reviews = append(reviews, fmt.Sprintf(`{"user": {"login": "a"}, "state": "REQUEST_CHANGES", "commit_id": "sha%d"}`, i))
+	}
+	server, _ := newGiteaServer(t, map[string]string{
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/commits":        `[` + giteaCommit1 + `]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1":                `{"id": 10, "number": 1, "state": "open", "head": {"sha": "sha1"}}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/reviews":        `[` + strings.Join(reviews, ",") + `]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/reviews?page=2": `[{"user": {"login": "a"}, "state": "APPROVED", "commit_id": "sha1"}]`,
+	})
+	defer server.Close()
+	client := newGiteaClient(t, server)
+
+	pr, err := client.GetPullRequest(context.TODO(), "1", "sha1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, pr.ApprovedReviewCount)
+	assert.Equal(t, 0, pr.ChangesRequestedCount)
+}
+
+func TestGiteaListCommits(t *testing.T) {
+	server, _ := newGiteaServer(t, map[string]string{
+		"GET /api/v1/repos/itsdalmo/test-repository/pulls/1/commits": `[` + giteaCommit1 + `, {"sha": "sha2", "commit": {"message": "commit 2"}}]`,
+	})
+	defer server.Close()
+	client := newGiteaClient(t, server)
+
+	commits, err := client.ListCommits(context.TODO(), "1")
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Equal(t, "sha1", commits[0].OID)
+	assert.Equal(t, "user", commits[0].Author.User.Login)
+	assert.Equal(t, "commit 2", commits[1].Message)
+}
+
+func TestGiteaComments(t *testing.T) {
+	server, requests := newGiteaServer(t, map[string]string{
+		"GET /api/v1/user": `{"login": "concourse"}`,
+		"GET /api/v1/repos/itsdalmo/test-repository/issues/1/comments": `[
+  {"id": 1, "user": {"login": "concourse"}},
+  {"id": 2, "user": {"login": "someone"}},
+  {"id": 3, "user": {"login": "concourse"}}
+]`,
+		"DELETE /api/v1/repos/itsdalmo/test-repository/issues/comments/1": ``,
+		"DELETE /api/v1/repos/itsdalmo/test-repository/issues/comments/3": ``,
+		"POST /api/v1/repos/itsdalmo/test-repository/issues/1/comments":   `{}`,
+		"POST /api/v1/repos/itsdalmo/test-repository/statuses/sha1":       `{}`,
+	})
+	defer server.Close()
+	client := newGiteaClient(t, server)
+
+	require.NoError(t, client.DeletePreviousComments(context.TODO(), "1"))
+	require.NoError(t, client.PostComment(context.TODO(), "1", "comment"))
+	require.NoError(t, client.UpdateCommitStatus(context.TODO(), "sha1", "concourse-ci", "status", "SUCCESS", "https://ci.example.com", "description"))
+
+	assert.Equal(t, []string{
+		"GET /api/v1/user",
+		"GET /api/v1/repos/itsdalmo/test-repository/issues/1/comments",
+		"DELETE /api/v1/repos/itsdalmo/test-repository/issues/comments/1",
+		"DELETE /api/v1/repos/itsdalmo/test-repository/issues/comments/3",
+		`POST /api/v1/repos/itsdalmo/test-repository/issues/1/comments {"body":"comment"}`,
+		`POST /api/v1/repos/itsdalmo/test-repository/statuses/sha1 {"context":"concourse-ci/status","description":"description","state":"success","target_url":"https://ci.example.com"}`,
+	}, *requests)
+}
+
+func TestGiteaTimeline(t *testing.T) {
+	server, _ := newGiteaServer(t, map[string]string{
+		"GET /api/v1/repos/itsdalmo/test-repository/issues/1/timeline": `[
+  {"id": 1, "type": "pull_push", "created_at": "2020-01-01T00:00:00Z", "user": {"login": "someone"}, "body": "{\"is_force_push\":false,\"commit_ids\":[\"sha1\",\"sha2\"]}"},
+  {"id": 2, "type": "label", "created_at": "2020-01-02T00:00:00Z", "user": {"login": "maintainer"}, "body": "1", "label": {"name": "ok-to-test"}},
+  {"id": 3, "type": "label", "created_at": "2020-01-03T00:00:00Z", "user": {"login": "maintainer"}, "body": "", "label": {"name": "ok-to-test"}},
+  {"id": 4, "type": "comment", "created_at": "2020-01-04T00:00:00Z", "user": {"login": "maintainer"}, "body": "/ok-to-test"},
+  {"id": 5, "type": "pull_push", "created_at": "2020-01-05T00:00:00Z", "user": {"login": "someone"}, "body": "{\"is_force_push\":true,\"commit_ids\":[\"sha2\",\"sha3\"]}"}
+]`,
+		"GET /api/v1/repos/itsdalmo/test-repository/collaborators/maintainer/permission": `{"permission": "write"}`,
+	})
+	defer server.Close()
+	client := newGiteaClient(t, server)
+
+	items, err := client.ListTimelineItems(context.TODO(), "1")
+	require.NoError(t, err)
+
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, []resource.TimelineItem{
+		{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, CreatedAt: day(1), Actor: "someone", Commit: "sha1"},
+		{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, CreatedAt: day(1), Actor: "someone", Commit: "sha2"},
+		{Type: githubv4.PullRequestTimelineItemsItemTypeLabeledEvent, CreatedAt: day(2), Actor: "maintainer", Label: "ok-to-test"},
+		{Type: githubv4.PullRequestTimelineItemsItemTypeIssueComment, CreatedAt: day(4), Actor: "maintainer", Body: "/ok-to-test", CommentID: 4},
+		{Type: githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent, CreatedAt: day(5), Actor: "someone", Commit: "sha3"},
+	}, items)
+
+	permission, err := client.GetPermission(context.TODO(), "maintainer")
+	require.NoError(t, err)
+	assert.Equal(t, "write", permission)
+
+	permission, err = client.GetPermission(context.TODO(), "stranger")
+	require.NoError(t, err)
+	assert.Equal(t, "none", permission)
+}
+
+func TestSourceValidateForge(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		wantErr     bool
+	}{
+		{
+			description: "github is the default",
+			source:      resource.Source{Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken"},
+		},
+		{
+			description: "gitea is valid",
+			source:      resource.Source{Forge: "gitea", Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken", V3Endpoint: "https://gitea.example.com/api/v1"},
+		},
+		{
+			description: "gitea requires an endpoint",
+			source:      resource.Source{Forge: "gitea", Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken"},
+			wantErr:     true,
+		},
+		{
+			description: "gitea does not support github apps",
+			source:      resource.Source{Forge: "gitea", Repository: "itsdalmo/test-repository", AppID: 1, InstallationID: 2, PrivateKey: "key", V3Endpoint: "https://gitea.example.com/api/v1"},
+			wantErr:     true,
+		},
+		{
+			description: "gitea does not support check runs",
+			source:      resource.Source{Forge: "gitea", Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken", V3Endpoint: "https://gitea.example.com/api/v1", RequiredCheckRuns: []string{"build"}},
+			wantErr:     true,
+		},
+		{
+			description: "gitea does not support require_up_to_date",
+			source:      resource.Source{Forge: "gitea", Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken", V3Endpoint: "https://gitea.example.com/api/v1", RequireUpToDate: true},
+			wantErr:     true,
+		},
+		{
+			description: "unknown forges are invalid",
+			source:      resource.Source{Forge: "gitlab", Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.source.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
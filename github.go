@@ -2,15 +2,16 @@ package resource
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v28/github"
 	"github.com/shurcooL/githubv4"
@@ -20,98 +21,140 @@ import (
 // Github for testing purposes.
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/fake_github.go . Github
 type Github interface {
-	ListPullRequests([]githubv4.PullRequestState) ([]*PullRequest, error)
-	ListModifiedFiles(int) ([]string, error)
-	PostComment(string, string) error
-	GetPullRequest(string, string) (*PullRequest, error)
-	GetChangedFiles(string, string) ([]ChangedFileObject, error)
-	UpdateCommitStatus(string, string, string, string, string, string) error
-	DeletePreviousComments(string) error
+	ListPullRequests(context.Context, []githubv4.PullRequestState, bool, time.Time) ([]*PullRequest, error)
+	PostComment(context.Context, string, string) error
+	GetPullRequest(context.Context, string, string) (*PullRequest, error)
+	ListCommits(context.Context, string) ([]CommitObject, error)
+	GetChangedFiles(context.Context, string, string) ([]ChangedFileObject, error)
+	UpdateCommitStatus(context.Context, string, string, string, string, string, string) error
+	DeletePreviousComments(context.Context, string) error
+	ListTimelineItems(context.Context, string) ([]TimelineItem, error)
+	GetPermission(context.Context, string) (string, error)
 }
 
 // GithubClient for handling requests to the Github V3 and V4 APIs.
 type GithubClient struct {
-	V3         *github.Client
-	V4         *githubv4.Client
-	Repository string
-	Owner      string
+	V3          *github.Client
+	V4          *githubv4.Client
+	TokenSource oauth2.TokenSource
+	Repository  string
+	Owner       string
 }
 
 // NewGithubClient ...
-func NewGithubClient(s *Source) (*GithubClient, error) {
+func NewGithubClient(ctx context.Context, s *Source) (*GithubClient, error) {
 	owner, repository, err := parseRepository(s.Repository)
 	if err != nil {
 		return nil, err
 	}
 
-	// Skip SSL verification for self-signed certificates
+	// Use a custom HTTP client for self-signed certificates, CAs and proxies
 	// source: https://github.com/google/go-github/pull/598#issuecomment-333039238
-	var ctx context.Context
-	if s.SkipSSLVerification {
-		insecureClient := &http.Client{Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-		}
-		ctx = context.WithValue(context.TODO(), oauth2.HTTPClient, insecureClient)
-	} else {
-		ctx = context.TODO()
+	transport, err := newTransport(s)
+	if err != nil {
+		return nil, err
 	}
+	// The token source keeps this context to mint installation tokens, so it
+	// must be the caller's context for minting to be cancelled along with it.
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: newRetryTransport(transport, s.MaxRetries, time.Duration(s.MaxRetryWait), time.Duration(s.RequestTimeout)),
+	})
 
-	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: s.AccessToken},
-	))
+	tokenSource, err := NewTokenSource(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	client := oauth2.NewClient(ctx, tokenSource)
 
-	var v3 *github.Client
-	if s.V3Endpoint != "" {
-		endpoint, err := url.Parse(s.V3Endpoint)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse v3 endpoint: %s", err)
-		}
-		v3, err = github.NewEnterpriseClient(endpoint.String(), endpoint.String(), client)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		v3 = github.NewClient(client)
+	v3, err := newV3Client(s.V3Endpoint, client)
+	if err != nil {
+		return nil, err
 	}
 
+	v4Client := &http.Client{Transport: &acceptTransport{base: client.Transport, accept: mergeInfoPreview}}
+
 	var v4 *githubv4.Client
 	if s.V4Endpoint != "" {
 		endpoint, err := url.Parse(s.V4Endpoint)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse v4 endpoint: %s", err)
 		}
-		v4 = githubv4.NewEnterpriseClient(endpoint.String(), client)
+		v4 = githubv4.NewEnterpriseClient(endpoint.String(), v4Client)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		v4 = githubv4.NewClient(client)
+		v4 = githubv4.NewClient(v4Client)
 	}
 
 	return &GithubClient{
-		V3:         v3,
-		V4:         v4,
-		Owner:      owner,
-		Repository: repository,
+		V3:          v3,
+		V4:          v4,
+		TokenSource: tokenSource,
+		Owner:       owner,
+		Repository:  repository,
 	}, nil
 }
 
-// ListPullRequests gets the last commit on all pull requests with the matching state.
-func (m *GithubClient) ListPullRequests(prStates []githubv4.PullRequestState) ([]*PullRequest, error) {
+// NewManager returns the client for the forge configured in the source, along
+// with the token source to use when cloning over HTTPS.
+func NewManager(ctx context.Context, s *Source) (Github, oauth2.TokenSource, error) {
+	if s.Forge == ForgeGitea {
+		client, err := NewGiteaClient(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, client.TokenSource, nil
+	}
+	client, err := NewGithubClient(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, client.TokenSource, nil
+}
+
+func newV3Client(v3Endpoint string, client *http.Client) (*github.Client, error) {
+	if v3Endpoint == "" {
+		return github.NewClient(client), nil
+	}
+	endpoint, err := url.Parse(v3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse v3 endpoint: %s", err)
+	}
+	return github.NewEnterpriseClient(endpoint.String(), endpoint.String(), client)
+}
+
+// ListPullRequests gets the last commit on all pull requests with the matching state,
+// and optionally the files changed in each pull request. Pull requests are listed
+// by when they were last updated, and listing stops at the first pull request
+// which has not been updated since the given time (unless it is zero).
+func (m *GithubClient) ListPullRequests(ctx context.Context, prStates []githubv4.PullRequestState, includeFiles bool, since time.Time) ([]*PullRequest, error) {
 	var query struct {
 		Repository struct {
 			PullRequests struct {
 				Edges []struct {
 					Node struct {
 						PullRequestObject
-						Reviews struct {
-							TotalCount int
-						} `graphql:"reviews(states: $prReviewStates)"`
+						LatestOpinionatedReviews struct {
+							Edges []struct {
+								Node struct {
+									ReviewObject
+								}
+							}
+						} `graphql:"latestOpinionatedReviews(first:$reviewsFirst)"`
 						Commits struct {
 							Edges []struct {
 								Node struct {
-									Commit CommitObject
+									Commit struct {
+										CommitObject
+										StatusCheckRollup struct {
+											Contexts struct {
+												Edges []struct {
+													Node statusCheckRollupContext
+												}
+											} `graphql:"contexts(first:$contextsFirst)"`
+										}
+									}
 								}
 							}
 						} `graphql:"commits(last:$commitsLast)"`
@@ -121,46 +164,158 @@ func (m *GithubClient) ListPullRequests(prStates []githubv4.PullRequestState) ([
 									LabelObject
 								}
 							}
+							PageInfo struct {
+								EndCursor   githubv4.String
+								HasNextPage bool
+							}
 						} `graphql:"labels(first:$labelsFirst)"`
+						Files struct {
+							Edges []struct {
+								Node struct {
+									ChangedFileObject
+								}
+							}
+							PageInfo struct {
+								EndCursor   githubv4.String
+								HasNextPage bool
+							}
+						} `graphql:"files(first:$filesFirst) @include(if:$includeFiles)"`
+						TimelineItems struct {
+							Nodes []timelineItemNode
+						} `graphql:"timelineItems(last:$recentItemsLast,since:$recentItemsSince,itemTypes:$recentItemTypes)"`
+						ForcePushes struct {
+							Nodes []timelineItemNode
+						} `graphql:"forcePushes: timelineItems(last:$forcePushesLast,itemTypes:$forcePushTypes)"`
 					}
 				}
 				PageInfo struct {
 					EndCursor   githubv4.String
 					HasNextPage bool
 				}
-			} `graphql:"pullRequests(first:$prFirst,states:$prStates,after:$prCursor)"`
+			} `graphql:"pullRequests(first:$prFirst,states:$prStates,after:$prCursor,orderBy:{field:UPDATED_AT,direction:DESC})"`
 		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+		RateLimit struct {
+			Cost      int
+			Remaining int
+		}
 	}
 
 	vars := map[string]interface{}{
-		"repositoryOwner": githubv4.String(m.Owner),
-		"repositoryName":  githubv4.String(m.Repository),
-		"prFirst":         githubv4.Int(100),
-		"prStates":        prStates,
-		"prCursor":        (*githubv4.String)(nil),
-		"commitsLast":     githubv4.Int(1),
-		"prReviewStates":  []githubv4.PullRequestReviewState{githubv4.PullRequestReviewStateApproved},
-		"labelsFirst":     githubv4.Int(100),
+		"repositoryOwner":  githubv4.String(m.Owner),
+		"repositoryName":   githubv4.String(m.Repository),
+		"prFirst":          githubv4.Int(100),
+		"prStates":         prStates,
+		"prCursor":         (*githubv4.String)(nil),
+		"commitsLast":      githubv4.Int(1),
+		"reviewsFirst":     githubv4.Int(100),
+		"contextsFirst":    githubv4.Int(100),
+		"labelsFirst":      githubv4.Int(100),
+		"filesFirst":       githubv4.Int(100),
+		"includeFiles":     githubv4.Boolean(includeFiles),
+		"recentItemsLast":  githubv4.Int(25),
+		"recentItemsSince": (*githubv4.DateTime)(nil),
+		"recentItemTypes": []githubv4.PullRequestTimelineItemsItemType{
+			githubv4.PullRequestTimelineItemsItemTypeIssueComment,
+			githubv4.PullRequestTimelineItemsItemTypeLabeledEvent,
+		},
+		// The last force push is listed on its own, so that it is not crowded out by recent items.
+		"forcePushesLast": githubv4.Int(1),
+		"forcePushTypes": []githubv4.PullRequestTimelineItemsItemType{
+			githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent,
+		},
+	}
+	if !since.IsZero() {
+		vars["recentItemsSince"] = githubv4.DateTime{Time: since}
 	}
 
 	var response []*PullRequest
+	var pages int
+	defer func() {
+		LoggerFromContext(ctx).Debug("listed pull requests", Fields{"pages": pages, "pull_requests": len(response)})
+	}()
 	for {
-		if err := m.V4.Query(context.TODO(), &query, vars); err != nil {
+		if err := m.query(ctx, "ListPullRequests", &query, vars); err != nil {
 			return nil, err
 		}
+		pages++
+		log.Printf("graphql rate limit: cost %d, remaining %d", query.RateLimit.Cost, query.RateLimit.Remaining)
 		for _, p := range query.Repository.PullRequests.Edges {
-			labels := make([]LabelObject, len(p.Node.Labels.Edges))
+			// Pull requests are ordered by updatedAt, so the rest are older as well.
+			if !since.IsZero() && !p.Node.UpdatedAt.After(since) {
+				return response, nil
+			}
+
+			var labels []LabelObject
 			for _, l := range p.Node.Labels.Edges {
 				labels = append(labels, l.Node.LabelObject)
 			}
+			if p.Node.Labels.PageInfo.HasNextPage {
+				rest, err := m.listLabels(ctx, p.Node.Number, string(p.Node.Labels.PageInfo.EndCursor))
+				if err != nil {
+					return nil, err
+				}
+				labels = append(labels, rest...)
+			}
+
+			var files []ChangedFileObject
+			if includeFiles {
+				for _, f := range p.Node.Files.Edges {
+					files = append(files, f.Node.ChangedFileObject)
+				}
+				// Only pull requests with more than 100 files need another round-trip.
+				if p.Node.Files.PageInfo.HasNextPage {
+					rest, err := m.listFiles(ctx, p.Node.Number, string(p.Node.Files.PageInfo.EndCursor))
+					if err != nil {
+						return nil, err
+					}
+					files = append(files, rest...)
+				}
+			}
+
+			var items []TimelineItem
+			for _, n := range append(p.Node.TimelineItems.Nodes, p.Node.ForcePushes.Nodes...) {
+				if item, ok := n.timelineItem(); ok {
+					items = append(items, item)
+				}
+			}
+
+			var reviews []ReviewObject
+			for _, r := range p.Node.LatestOpinionatedReviews.Edges {
+				reviews = append(reviews, r.Node.ReviewObject)
+			}
 
 			for _, c := range p.Node.Commits.Edges {
-				response = append(response, &PullRequest{
-					PullRequestObject:   p.Node.PullRequestObject,
-					Tip:                 c.Node.Commit,
-					ApprovedReviewCount: p.Node.Reviews.TotalCount,
-					Labels:              labels,
-				})
+				approved, changesRequested := countReviews(reviews, c.Node.Commit.OID)
+				pr := &PullRequest{
+					PullRequestObject:     p.Node.PullRequestObject,
+					Tip:                   c.Node.Commit.CommitObject,
+					ApprovedReviewCount:   approved,
+					ChangesRequestedCount: changesRequested,
+					Labels:                labels,
+					Files:                 files,
+					TimelineItems:         items,
+				}
+				for _, rc := range c.Node.Commit.StatusCheckRollup.Contexts.Edges {
+					switch rc.Node.Typename {
+					case "StatusContext":
+						pr.Statuses = append(pr.Statuses, StatusObject{
+							Name:        rc.Node.StatusContext.Context,
+							State:       rc.Node.StatusContext.State,
+							CompletedAt: rc.Node.StatusContext.CreatedAt.Time,
+						})
+					case "CheckRun":
+						state := rc.Node.CheckRun.Conclusion
+						if state == "" {
+							state = rc.Node.CheckRun.Status
+						}
+						run := StatusObject{Name: rc.Node.CheckRun.Name, State: state}
+						if rc.Node.CheckRun.CompletedAt != nil {
+							run.CompletedAt = rc.Node.CheckRun.CompletedAt.Time
+						}
+						pr.CheckRuns = append(pr.CheckRuns, run)
+					}
+				}
+				response = append(response, pr)
 			}
 		}
 		if !query.Repository.PullRequests.PageInfo.HasNextPage {
@@ -171,44 +326,15 @@ func (m *GithubClient) ListPullRequests(prStates []githubv4.PullRequestState) ([
 	return response, nil
 }
 
-// ListModifiedFiles in a pull request (not supported by V4 API).
-func (m *GithubClient) ListModifiedFiles(prNumber int) ([]string, error) {
-	var files []string
-
-	opt := &github.ListOptions{
-		PerPage: 100,
-	}
-	for {
-		result, response, err := m.V3.PullRequests.ListFiles(
-			context.TODO(),
-			m.Owner,
-			m.Repository,
-			prNumber,
-			opt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		for _, f := range result {
-			files = append(files, *f.Filename)
-		}
-		if response.NextPage == 0 {
-			break
-		}
-		opt.Page = response.NextPage
-	}
-	return files, nil
-}
-
 // PostComment to a pull request or issue.
-func (m *GithubClient) PostComment(prNumber, comment string) error {
+func (m *GithubClient) PostComment(ctx context.Context, prNumber, comment string) error {
 	pr, err := strconv.Atoi(prNumber)
 	if err != nil {
 		return fmt.Errorf("failed to convert pull request number to int: %s", err)
 	}
 
 	_, _, err = m.V3.Issues.CreateComment(
-		context.TODO(),
+		ctx,
 		m.Owner,
 		m.Repository,
 		pr,
@@ -220,12 +346,16 @@ func (m *GithubClient) PostComment(prNumber, comment string) error {
 }
 
 // GetChangedFiles ...
-func (m *GithubClient) GetChangedFiles(prNumber string, commitRef string) ([]ChangedFileObject, error) {
+func (m *GithubClient) GetChangedFiles(ctx context.Context, prNumber string, commitRef string) ([]ChangedFileObject, error) {
 	pr, err := strconv.Atoi(prNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert pull request number to int: %s", err)
 	}
+	return m.listFiles(ctx, pr, "")
+}
 
+// listFiles pages through the files changed in a pull request, starting after the given cursor.
+func (m *GithubClient) listFiles(ctx context.Context, prNumber int, offset string) ([]ChangedFileObject, error) {
 	var cfo []ChangedFileObject
 
 	var filequery struct {
@@ -246,18 +376,16 @@ func (m *GithubClient) GetChangedFiles(prNumber string, commitRef string) ([]Cha
 		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
 	}
 
-	offset := ""
-
 	for {
 		vars := map[string]interface{}{
 			"repositoryOwner":       githubv4.String(m.Owner),
 			"repositoryName":        githubv4.String(m.Repository),
-			"prNumber":              githubv4.Int(pr),
+			"prNumber":              githubv4.Int(prNumber),
 			"changedFilesFirst":     githubv4.Int(100),
 			"changedFilesEndCursor": githubv4.String(offset),
 		}
 
-		if err := m.V4.Query(context.TODO(), &filequery, vars); err != nil {
+		if err := m.query(ctx, "ListFiles", &filequery, vars); err != nil {
 			return nil, err
 		}
 
@@ -275,8 +403,57 @@ func (m *GithubClient) GetChangedFiles(prNumber string, commitRef string) ([]Cha
 	return cfo, nil
 }
 
+// listLabels pages through the labels on a pull request, starting after the given cursor.
+func (m *GithubClient) listLabels(ctx context.Context, prNumber int, offset string) ([]LabelObject, error) {
+	var labels []LabelObject
+
+	var labelquery struct {
+		Repository struct {
+			PullRequest struct {
+				Labels struct {
+					Edges []struct {
+						Node struct {
+							LabelObject
+						}
+					}
+					PageInfo struct {
+						EndCursor   githubv4.String
+						HasNextPage bool
+					}
+				} `graphql:"labels(first:$labelsFirst, after:$labelsEndCursor)"`
+			} `graphql:"pullRequest(number:$prNumber)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+
+	for {
+		vars := map[string]interface{}{
+			"repositoryOwner": githubv4.String(m.Owner),
+			"repositoryName":  githubv4.String(m.Repository),
+			"prNumber":        githubv4.Int(prNumber),
+			"labelsFirst":     githubv4.Int(100),
+			"labelsEndCursor": githubv4.String(offset),
+		}
+
+		if err := m.query(ctx, "ListLabels", &labelquery, vars); err != nil {
+			return nil, err
+		}
+
+		for _, l := range labelquery.Repository.PullRequest.Labels.Edges {
+			labels = append(labels, l.Node.LabelObject)
+		}
+
+		if !labelquery.Repository.PullRequest.Labels.PageInfo.HasNextPage {
+			break
+		}
+
+		offset = string(labelquery.Repository.PullRequest.Labels.PageInfo.EndCursor)
+	}
+
+	return labels, nil
+}
+
 // GetPullRequest ...
-func (m *GithubClient) GetPullRequest(prNumber, commitRef string) (*PullRequest, error) {
+func (m *GithubClient) GetPullRequest(ctx context.Context, prNumber, commitRef string) (*PullRequest, error) {
 	pr, err := strconv.Atoi(prNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert pull request number to int: %s", err)
@@ -292,7 +469,11 @@ func (m *GithubClient) GetPullRequest(prNumber, commitRef string) (*PullRequest,
 							Commit CommitObject
 						}
 					}
-				} `graphql:"commits(last:$commitsLast)"`
+					PageInfo struct {
+						StartCursor     githubv4.String
+						HasPreviousPage bool
+					}
+				} `graphql:"commits(last:$commitsLast,before:$commitsCursor)"`
 			} `graphql:"pullRequest(number:$prNumber)"`
 		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
 	}
@@ -302,47 +483,90 @@ func (m *GithubClient) GetPullRequest(prNumber, commitRef string) (*PullRequest,
 		"repositoryName":  githubv4.String(m.Repository),
 		"prNumber":        githubv4.Int(pr),
 		"commitsLast":     githubv4.Int(100),
+		"commitsCursor":   (*githubv4.String)(nil),
 	}
 
-	// TODO: Pagination - in case someone pushes > 100 commits before the build has time to start :p
-	if err := m.V4.Query(context.TODO(), &query, vars); err != nil {
-		return nil, err
-	}
+	// Page backwards from the latest commit, since the requested commit is most likely a recent one.
+	for {
+		if err := m.query(ctx, "GetPullRequest", &query, vars); err != nil {
+			return nil, err
+		}
 
-	for _, c := range query.Repository.PullRequest.Commits.Edges {
-		if c.Node.Commit.OID == commitRef {
-			// Return as soon as we find the correct ref.
-			return &PullRequest{
-				PullRequestObject: query.Repository.PullRequest.PullRequestObject,
-				Tip:               c.Node.Commit,
-			}, nil
+		for _, c := range query.Repository.PullRequest.Commits.Edges {
+			if c.Node.Commit.OID == commitRef {
+				// Return as soon as we find the correct ref.
+				return &PullRequest{
+					PullRequestObject: query.Repository.PullRequest.PullRequestObject,
+					Tip:               c.Node.Commit,
+				}, nil
+			}
+		}
+
+		if !query.Repository.PullRequest.Commits.PageInfo.HasPreviousPage {
+			break
 		}
+		vars["commitsCursor"] = query.Repository.PullRequest.Commits.PageInfo.StartCursor
 	}
 
 	// Return an error if the commit was not found
 	return nil, fmt.Errorf("commit with ref '%s' does not exist", commitRef)
 }
 
-// UpdateCommitStatus for a given commit (not supported by V4 API).
-func (m *GithubClient) UpdateCommitStatus(commitRef, baseContext, statusContext, status, targetURL, description string) error {
-	if baseContext == "" {
-		baseContext = "concourse-ci"
+// ListCommits returns the commits of a pull request, oldest first.
+func (m *GithubClient) ListCommits(ctx context.Context, prNumber string) ([]CommitObject, error) {
+	pr, err := strconv.Atoi(prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert pull request number to int: %s", err)
 	}
 
-	if statusContext == "" {
-		statusContext = "status"
+	var query struct {
+		Repository struct {
+			PullRequest struct {
+				Commits struct {
+					Edges []struct {
+						Node struct {
+							Commit CommitObject
+						}
+					}
+					PageInfo struct {
+						EndCursor   githubv4.String
+						HasNextPage bool
+					}
+				} `graphql:"commits(first:$commitsFirst,after:$commitsCursor)"`
+			} `graphql:"pullRequest(number:$prNumber)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
 	}
 
-	if targetURL == "" {
-		targetURL = strings.Join([]string{os.Getenv("ATC_EXTERNAL_URL"), "builds", os.Getenv("BUILD_ID")}, "/")
+	vars := map[string]interface{}{
+		"repositoryOwner": githubv4.String(m.Owner),
+		"repositoryName":  githubv4.String(m.Repository),
+		"prNumber":        githubv4.Int(pr),
+		"commitsFirst":    githubv4.Int(100),
+		"commitsCursor":   (*githubv4.String)(nil),
 	}
 
-	if description == "" {
-		description = fmt.Sprintf("Concourse CI build %s", status)
+	var commits []CommitObject
+	for {
+		if err := m.query(ctx, "ListCommits", &query, vars); err != nil {
+			return nil, err
+		}
+		for _, c := range query.Repository.PullRequest.Commits.Edges {
+			commits = append(commits, c.Node.Commit)
+		}
+		if !query.Repository.PullRequest.Commits.PageInfo.HasNextPage {
+			break
+		}
+		vars["commitsCursor"] = query.Repository.PullRequest.Commits.PageInfo.EndCursor
 	}
+	return commits, nil
+}
+
+// UpdateCommitStatus for a given commit (not supported by V4 API).
+func (m *GithubClient) UpdateCommitStatus(ctx context.Context, commitRef, baseContext, statusContext, status, targetURL, description string) error {
+	statusContext, targetURL, description = statusDefaults(baseContext, statusContext, status, targetURL, description)
 
 	_, _, err := m.V3.Repositories.CreateStatus(
-		context.TODO(),
+		ctx,
 		m.Owner,
 		m.Repository,
 		commitRef,
@@ -350,13 +574,34 @@ func (m *GithubClient) UpdateCommitStatus(commitRef, baseContext, statusContext,
 			State:       github.String(strings.ToLower(status)),
 			TargetURL:   github.String(targetURL),
 			Description: github.String(description),
-			Context:     github.String(path.Join(baseContext, statusContext)),
+			Context:     github.String(statusContext),
 		},
 	)
 	return err
 }
 
-func (m *GithubClient) DeletePreviousComments(prNumber string) error {
+// statusDefaults returns the full context, target URL and description of a
+// commit status, using the Concourse build for any values that are not set.
+func statusDefaults(baseContext, statusContext, status, targetURL, description string) (string, string, string) {
+	if baseContext == "" {
+		baseContext = "concourse-ci"
+	}
+
+	if statusContext == "" {
+		statusContext = "status"
+	}
+
+	if targetURL == "" {
+		targetURL = strings.Join([]string{os.Getenv("ATC_EXTERNAL_URL"), "builds", os.Getenv("BUILD_ID")}, "/")
+	}
+
+	if description == "" {
+		description = fmt.Sprintf("Concourse CI build %s", status)
+	}
+	return path.Join(baseContext, statusContext), targetURL, description
+}
+
+func (m *GithubClient) DeletePreviousComments(ctx context.Context, prNumber string) error {
 	pr, err := strconv.Atoi(prNumber)
 	if err != nil {
 		return fmt.Errorf("failed to convert pull request number to int: %s", err)
@@ -378,7 +623,11 @@ func (m *GithubClient) DeletePreviousComments(prNumber string) error {
 							}
 						}
 					}
-				} `graphql:"comments(last:$commentsLast)"`
+					PageInfo struct {
+						EndCursor   githubv4.String
+						HasNextPage bool
+					}
+				} `graphql:"comments(first:$commentsFirst,after:$commentsCursor)"`
 			} `graphql:"pullRequest(number:$prNumber)"`
 		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
 	}
@@ -387,25 +636,231 @@ func (m *GithubClient) DeletePreviousComments(prNumber string) error {
 		"repositoryOwner": githubv4.String(m.Owner),
 		"repositoryName":  githubv4.String(m.Repository),
 		"prNumber":        githubv4.Int(pr),
-		"commentsLast":    githubv4.Int(100),
+		"commentsFirst":   githubv4.Int(100),
+		"commentsCursor":  (*githubv4.String)(nil),
 	}
 
-	if err := m.V4.Query(context.TODO(), &getComments, vars); err != nil {
-		return err
+	// Collect all comments before deleting any, so that deletions do not shift the pages.
+	var previous []int64
+	for {
+		if err := m.query(ctx, "GetComments", &getComments, vars); err != nil {
+			return err
+		}
+		for _, e := range getComments.Repository.PullRequest.Comments.Edges {
+			if e.Node.Author.Login == getComments.Viewer.Login {
+				previous = append(previous, e.Node.DatabaseId)
+			}
+		}
+		if !getComments.Repository.PullRequest.Comments.PageInfo.HasNextPage {
+			break
+		}
+		vars["commentsCursor"] = getComments.Repository.PullRequest.Comments.PageInfo.EndCursor
 	}
 
-	for _, e := range getComments.Repository.PullRequest.Comments.Edges {
-		if e.Node.Author.Login == getComments.Viewer.Login {
-			_, err := m.V3.Issues.DeleteComment(context.TODO(), m.Owner, m.Repository, e.Node.DatabaseId)
-			if err != nil {
-				return err
-			}
+	for _, id := range previous {
+		_, err := m.V3.Issues.DeleteComment(ctx, m.Owner, m.Repository, id)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// ListTimelineItems returns the labels, comments and pushes on the timeline of a pull request, in order.
+func (m *GithubClient) ListTimelineItems(ctx context.Context, prNumber string) ([]TimelineItem, error) {
+	pr, err := strconv.Atoi(prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert pull request number to int: %s", err)
+	}
+
+	var query struct {
+		Repository struct {
+			PullRequest struct {
+				TimelineItems struct {
+					Nodes    []timelineItemNode
+					PageInfo struct {
+						EndCursor   githubv4.String
+						HasNextPage bool
+					}
+				} `graphql:"timelineItems(first:$timelineFirst,after:$timelineCursor,itemTypes:$timelineTypes)"`
+			} `graphql:"pullRequest(number:$prNumber)"`
+		} `graphql:"repository(owner:$repositoryOwner,name:$repositoryName)"`
+	}
+
+	vars := map[string]interface{}{
+		"repositoryOwner": githubv4.String(m.Owner),
+		"repositoryName":  githubv4.String(m.Repository),
+		"prNumber":        githubv4.Int(pr),
+		"timelineFirst":   githubv4.Int(100),
+		"timelineCursor":  (*githubv4.String)(nil),
+		"timelineTypes": []githubv4.PullRequestTimelineItemsItemType{
+			githubv4.PullRequestTimelineItemsItemTypeLabeledEvent,
+			githubv4.PullRequestTimelineItemsItemTypeIssueComment,
+			githubv4.PullRequestTimelineItemsItemTypePullRequestCommit,
+			githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent,
+		},
+	}
+
+	var items []TimelineItem
+	for {
+		if err := m.query(ctx, "ListTimelineItems", &query, vars); err != nil {
+			return nil, err
+		}
+		for _, n := range query.Repository.PullRequest.TimelineItems.Nodes {
+			if item, ok := n.timelineItem(); ok {
+				items = append(items, item)
+			}
+		}
+		if !query.Repository.PullRequest.TimelineItems.PageInfo.HasNextPage {
+			break
+		}
+		vars["timelineCursor"] = query.Repository.PullRequest.TimelineItems.PageInfo.EndCursor
+	}
+	return items, nil
+}
+
+// timelineItemNode is a node from the timeline of a pull request.
+type timelineItemNode struct {
+	Typename     string `graphql:"__typename"`
+	LabeledEvent struct {
+		CreatedAt githubv4.DateTime
+		Actor     struct {
+			Login string
+		}
+		Label struct {
+			Name string
+		}
+	} `graphql:"... on LabeledEvent"`
+	IssueComment struct {
+		DatabaseID int64 `graphql:"databaseId"`
+		CreatedAt  githubv4.DateTime
+		Author     struct {
+			Login string
+		}
+		AuthorAssociation githubv4.CommentAuthorAssociation
+		Body              string
+	} `graphql:"... on IssueComment"`
+	PullRequestCommit struct {
+		Commit struct {
+			OID string
+		}
+	} `graphql:"... on PullRequestCommit"`
+	HeadRefForcePushedEvent struct {
+		CreatedAt githubv4.DateTime
+		Actor     struct {
+			Login string
+		}
+		AfterCommit struct {
+			OID string
+		}
+	} `graphql:"... on HeadRefForcePushedEvent"`
+}
+
+// timelineItem converts the node to a TimelineItem, and returns false for unknown types.
+func (n timelineItemNode) timelineItem() (TimelineItem, bool) {
+	// The fields of all fragments are decoded into each of them, so only the typename can be trusted.
+	switch n.Typename {
+	case "LabeledEvent":
+		return TimelineItem{
+			Type:      githubv4.PullRequestTimelineItemsItemTypeLabeledEvent,
+			CreatedAt: n.LabeledEvent.CreatedAt.Time,
+			Actor:     n.LabeledEvent.Actor.Login,
+			Label:     n.LabeledEvent.Label.Name,
+		}, true
+	case "IssueComment":
+		return TimelineItem{
+			Type:              githubv4.PullRequestTimelineItemsItemTypeIssueComment,
+			CreatedAt:         n.IssueComment.CreatedAt.Time,
+			Actor:             n.IssueComment.Author.Login,
+			AuthorAssociation: n.IssueComment.AuthorAssociation,
+			Body:              n.IssueComment.Body,
+			CommentID:         n.IssueComment.DatabaseID,
+		}, true
+	case "PullRequestCommit":
+		// Github does not tell when commits were pushed (pushedDate is deprecated, and null on
+		// github.com), and the commit date is set by the author, so the push is left untimed.
+		return TimelineItem{
+			Type:   githubv4.PullRequestTimelineItemsItemTypePullRequestCommit,
+			Commit: n.PullRequestCommit.Commit.OID,
+		}, true
+	case "HeadRefForcePushedEvent":
+		return TimelineItem{
+			Type:      githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent,
+			CreatedAt: n.HeadRefForcePushedEvent.CreatedAt.Time,
+			Actor:     n.HeadRefForcePushedEvent.Actor.Login,
+			Commit:    n.HeadRefForcePushedEvent.AfterCommit.OID,
+		}, true
+	}
+	return TimelineItem{}, false
+}
+
+// GetPermission returns the permission a user has on the repository (admin, write, read or none).
+func (m *GithubClient) GetPermission(ctx context.Context, login string) (string, error) {
+	permission, _, err := m.V3.Repositories.GetPermissionLevel(ctx, m.Owner, m.Repository, login)
+	if err != nil {
+		return "", err
+	}
+	return permission.GetPermission(), nil
+}
+
+// query runs a GraphQL query, logging its name, variables and duration when debugging.
+func (m *GithubClient) query(ctx context.Context, name string, q interface{}, vars map[string]interface{}) error {
+	start := time.Now()
+	err := m.V4.Query(ctx, q, vars)
+
+	fields := Fields{"query": name, "variables": vars, "duration_ms": milliseconds(start)}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	LoggerFromContext(ctx).Debug("graphql query", fields)
+	return err
+}
+
+// statusCheckRollupContext is a status context or check run in the status
+// check rollup of a commit.
+type statusCheckRollupContext struct {
+	Typename      string `graphql:"__typename"`
+	StatusContext struct {
+		Context   string
+		State     string
+		CreatedAt githubv4.DateTime
+	} `graphql:"... on StatusContext"`
+	CheckRun struct {
+		Name        string
+		Status      string
+		Conclusion  string
+		CompletedAt *githubv4.DateTime
+	} `graphql:"... on CheckRun"`
+}
+
+// countReviews returns the number of reviewers whose latest review approves the
+// given commit, and the number of reviewers whose latest review requests changes.
+// Reviews are expected in chronological order.
+func countReviews(reviews []ReviewObject, commit string) (int, int) {
+	latest := make(map[string]ReviewObject)
+	var reviewers []string
+	for _, r := range reviews {
+		if _, ok := latest[r.Author.Login]; !ok {
+			reviewers = append(reviewers, r.Author.Login)
+		}
+		latest[r.Author.Login] = r
+	}
+
+	var approved, changesRequested int
+	for _, login := range reviewers {
+		switch r := latest[login]; r.State {
+		case githubv4.PullRequestReviewStateApproved:
+			if r.Commit.OID == commit {
+				approved++
+			}
+		case githubv4.PullRequestReviewStateChangesRequested:
+			changesRequested++
+		}
+	}
+	return approved, changesRequested
+}
+
 func parseRepository(s string) (string, string, error) {
 	parts := strings.Split(s, "/")
 	if len(parts) != 2 {
@@ -0,0 +1,666 @@
+package resource_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestGithubClientRetries(t *testing.T) {
+	tests := []struct {
+		description string
+		maxRetries  int
+		statuses    []int
+		headers     map[string]string
+		call        func(*resource.GithubClient) error
+		wantErr     bool
+		wantCalls   int
+	}{
+		{
+			description: "retries graphql queries on secondary rate limits",
+			statuses:    []int{http.StatusForbidden, http.StatusForbidden},
+			headers:     map[string]string{"Retry-After": "0"},
+			call:        listPullRequests,
+			wantCalls:   3,
+		},
+		{
+			description: "retries graphql queries on bad gateway",
+			statuses:    []int{http.StatusBadGateway},
+			call:        listPullRequests,
+			wantCalls:   2,
+		},
+		{
+			description: "gives up after max retries",
+			maxRetries:  1,
+			statuses:    []int{http.StatusForbidden, http.StatusForbidden, http.StatusForbidden},
+			headers:     map[string]string{"Retry-After": "0"},
+			call:        listPullRequests,
+			wantErr:     true,
+			wantCalls:   2,
+		},
+		{
+			description: "does not retry when disabled",
+			maxRetries:  -1,
+			statuses:    []int{http.StatusForbidden},
+			headers:     map[string]string{"Retry-After": "0"},
+			call:        listPullRequests,
+			wantErr:     true,
+			wantCalls:   1,
+		},
+		{
+			description: "does not retry forbidden without rate limit headers",
+			statuses:    []int{http.StatusForbidden},
+			call:        listPullRequests,
+			wantErr:     true,
+			wantCalls:   1,
+		},
+		{
+			description: "does not retry non-idempotent v3 requests",
+			statuses:    []int{http.StatusBadGateway},
+			call: func(c *resource.GithubClient) error {
+				return c.PostComment(context.TODO(), "1", "comment")
+			},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			description: "does not retry v3 writes",
+			statuses:    []int{0, http.StatusBadGateway},
+			call: func(c *resource.GithubClient) error {
+				return c.DeletePreviousComments(context.TODO(), "1")
+			},
+			wantErr:   true,
+			wantCalls: 2,
+		},
+		{
+			description: "retries v3 reads",
+			statuses:    []int{http.StatusForbidden},
+			headers:     map[string]string{"Retry-After": "0"},
+			call: func(c *resource.GithubClient) error {
+				_, err := c.GetPermission(context.TODO(), "maintainer")
+				return err
+			},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var calls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= len(tc.statuses) && tc.statuses[calls-1] != 0 {
+					for k, v := range tc.headers {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(tc.statuses[calls-1])
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/graphql":
+					body, _ := ioutil.ReadAll(r.Body)
+					if strings.Contains(string(body), "viewer") {
+						w.Write([]byte(`{"data":{"viewer":{"login":"concourse"},"repository":{"pullRequest":{"id":"pr1","comments":{"edges":[{"node":{"databaseId":1,"author":{"login":"concourse"}}}]}}}}}`))
+						return
+					}
+					w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+				default:
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer server.Close()
+
+			client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V3Endpoint:  server.URL,
+				V4Endpoint:  server.URL + "/graphql",
+				MaxRetries:  tc.maxRetries,
+			})
+			require.NoError(t, err)
+
+			err = tc.call(client)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantCalls, calls)
+		})
+	}
+}
+
+func listPullRequests(c *resource.GithubClient) error {
+	_, err := c.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+	return err
+}
+
+func TestListPullRequestsFiles(t *testing.T) {
+	var queries []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		queries = append(queries, body.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(body.Query, "pullRequests(") {
+			w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[
+				{"node":{"number":1,"commits":{"edges":[{"node":{"commit":{"oid":"oid1"}}}]},"files":{"edges":[{"node":{"path":"a.go"}}],"pageInfo":{"hasNextPage":false}}}},
+				{"node":{"number":2,"commits":{"edges":[{"node":{"commit":{"oid":"oid2"}}}]},"files":{"edges":[{"node":{"path":"b.go"}}],"pageInfo":{"endCursor":"cursor1","hasNextPage":true}}}}
+			],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"repository":{"pullRequest":{"files":{"edges":[{"node":{"path":"c.go"}}],"pageInfo":{"hasNextPage":false}}}}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	pulls, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, true, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, pulls, 2)
+
+	assert.Equal(t, []resource.ChangedFileObject{{Path: "a.go"}}, pulls[0].Files)
+	assert.Equal(t, []resource.ChangedFileObject{{Path: "b.go"}, {Path: "c.go"}}, pulls[1].Files)
+
+	// One query for the pull requests, and one for the pull request which overflowed.
+	if assert.Len(t, queries, 2) {
+		assert.Equal(t, true, queries[0]["includeFiles"])
+		assert.Equal(t, float64(2), queries[1]["prNumber"])
+		assert.Equal(t, "cursor1", queries[1]["changedFilesEndCursor"])
+	}
+}
+
+func TestListPullRequestsReviews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), "latestOpinionatedReviews(first:$reviewsFirst)")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[
+			{"node":{"number":1,"reviewDecision":"CHANGES_REQUESTED","latestOpinionatedReviews":{"edges":[
+				{"node":{"author":{"login":"a"},"state":"APPROVED","commit":{"oid":"oid1"}}},
+				{"node":{"author":{"login":"b"},"state":"APPROVED","commit":{"oid":"oid0"}}},
+				{"node":{"author":{"login":"c"},"state":"CHANGES_REQUESTED","commit":{"oid":"oid0"}}},
+				{"node":{"author":{"login":"d"},"state":"APPROVED","commit":{"oid":"oid1"}}},
+				{"node":{"author":{"login":"d"},"state":"CHANGES_REQUESTED","commit":{"oid":"oid1"}}}
+			]},"commits":{"edges":[{"node":{"commit":{"oid":"oid1"}}}]}}}
+		],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	pulls, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, pulls, 1)
+
+	// Approvals of older commits and approvals superseded by a later review are not counted.
+	assert.Equal(t, 1, pulls[0].ApprovedReviewCount)
+	assert.Equal(t, 2, pulls[0].ChangesRequestedCount)
+	assert.Equal(t, githubv4.PullRequestReviewDecisionChangesRequested, pulls[0].ReviewDecision)
+}
+
+func TestListPullRequestsStatuses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), "contexts(first:$contextsFirst)")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[
+			{"node":{"number":1,"commits":{"edges":[{"node":{"commit":{"oid":"oid1","statusCheckRollup":{"contexts":{"edges":[
+				{"node":{"__typename":"StatusContext","context":"lint","state":"SUCCESS","createdAt":"2020-01-02T00:00:00Z"}},
+				{"node":{"__typename":"CheckRun","name":"build","status":"IN_PROGRESS","conclusion":null,"completedAt":null}},
+				{"node":{"__typename":"CheckRun","name":"test","status":"COMPLETED","conclusion":"FAILURE","completedAt":"2020-01-03T00:00:00Z"}}
+			]}}}}}]}}},
+			{"node":{"number":2,"commits":{"edges":[{"node":{"commit":{"oid":"oid2","statusCheckRollup":null}}}]}}}
+		],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	pulls, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, pulls, 2)
+
+	assert.Equal(t, "oid1", pulls[0].Tip.OID)
+	assert.Equal(t, []resource.StatusObject{{Name: "lint", State: "SUCCESS", CompletedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}}, pulls[0].Statuses)
+	assert.Equal(t, []resource.StatusObject{
+		{Name: "build", State: "IN_PROGRESS"},
+		{Name: "test", State: "FAILURE", CompletedAt: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}, pulls[0].CheckRuns)
+	assert.Empty(t, pulls[1].Statuses)
+	assert.Empty(t, pulls[1].CheckRuns)
+}
+
+func TestListPullRequestsMergeability(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// mergeStateStatus is only available with the merge info preview.
+		assert.Equal(t, "application/vnd.github.merge-info-preview+json", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[
+			{"node":{"number":1,"mergeable":"CONFLICTING","mergeStateStatus":"DIRTY","commits":{"edges":[{"node":{"commit":{"oid":"oid1"}}}]}}}
+		],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	pulls, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, pulls, 1)
+
+	assert.Equal(t, githubv4.MergeableStateConflicting, pulls[0].Mergeable)
+	assert.Equal(t, "DIRTY", pulls[0].MergeStateStatus)
+}
+
+func TestListCommits(t *testing.T) {
+	var cursors []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Contains(t, body.Query, "commits(first:$commitsFirst,after:$commitsCursor)")
+		cursors = append(cursors, body.Variables["commitsCursor"])
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Variables["commitsCursor"] == nil {
+			w.Write([]byte(`{"data":{"repository":{"pullRequest":{"commits":{"edges":[
+				{"node":{"commit":{"oid":"oid1","message":"first"}}}
+			],"pageInfo":{"endCursor":"cursor1","hasNextPage":true}}}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"repository":{"pullRequest":{"commits":{"edges":[
+			{"node":{"commit":{"oid":"oid2","message":"second"}}}
+		],"pageInfo":{"hasNextPage":false}}}}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	commits, err := client.ListCommits(context.TODO(), "1")
+	require.NoError(t, err)
+	if assert.Len(t, commits, 2) {
+		assert.Equal(t, "oid1", commits[0].OID)
+		assert.Equal(t, "second", commits[1].Message)
+	}
+	assert.Equal(t, []interface{}{nil, "cursor1"}, cursors)
+}
+
+func TestListPullRequestsTimelineItems(t *testing.T) {
+	var variables []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Contains(t, body.Query, "timelineItems(last:$recentItemsLast,since:$recentItemsSince,itemTypes:$recentItemTypes)")
+		assert.Contains(t, body.Query, "forcePushes: timelineItems(last:$forcePushesLast,itemTypes:$forcePushTypes)")
+		variables = append(variables, body.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[
+			{"node":{"number":1,"updatedAt":"2020-01-03T00:00:00Z","timelineItems":{"nodes":[
+				{"__typename":"IssueComment","databaseId":5,"createdAt":"2020-01-03T00:00:00Z","author":{"login":"maintainer"},"body":"/retest"},
+				{"__typename":"LabeledEvent","createdAt":"2020-01-03T01:00:00Z","actor":{"login":"maintainer"},"label":{"name":"deploy-preview"}}
+			]},"forcePushes":{"nodes":[
+				{"__typename":"HeadRefForcePushedEvent","createdAt":"2020-01-03T02:00:00Z","actor":{"login":"author"},"afterCommit":{"oid":"oid1"}}
+			]},"commits":{"edges":[{"node":{"commit":{"oid":"oid1","committedDate":"2020-01-01T00:00:00Z"}}}]}}}
+		],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	since := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+	pulls, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, since)
+	require.NoError(t, err)
+	require.Len(t, pulls, 1)
+
+	assert.Equal(t, []resource.TimelineItem{{
+		Type:      githubv4.PullRequestTimelineItemsItemTypeIssueComment,
+		CreatedAt: time.Date(2020, time.January, 3, 0, 0, 0, 0, time.UTC),
+		Actor:     "maintainer",
+		Body:      "/retest",
+		CommentID: 5,
+	}, {
+		Type:      githubv4.PullRequestTimelineItemsItemTypeLabeledEvent,
+		CreatedAt: time.Date(2020, time.January, 3, 1, 0, 0, 0, time.UTC),
+		Actor:     "maintainer",
+		Label:     "deploy-preview",
+	}, {
+		Type:      githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent,
+		CreatedAt: time.Date(2020, time.January, 3, 2, 0, 0, 0, time.UTC),
+		Actor:     "author",
+		Commit:    "oid1",
+	}}, pulls[0].TimelineItems)
+	assert.Equal(t, time.Date(2020, time.January, 3, 2, 0, 0, 0, time.UTC), pulls[0].UpdatedDate().Time)
+	if assert.Len(t, variables, 1) {
+		assert.Equal(t, "2020-01-02T00:00:00Z", variables[0]["recentItemsSince"])
+		assert.Equal(t, []interface{}{"ISSUE_COMMENT", "LABELED_EVENT"}, variables[0]["recentItemTypes"])
+		assert.Equal(t, []interface{}{"HEAD_REF_FORCE_PUSHED_EVENT"}, variables[0]["forcePushTypes"])
+	}
+}
+
+func TestListPullRequestsStopsAtOlderPullRequests(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), "orderBy:{field:UPDATED_AT,direction:DESC}")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[
+			{"node":{"number":2,"updatedAt":"2020-01-03T00:00:00Z","commits":{"edges":[{"node":{"commit":{"oid":"oid2"}}}]}}},
+			{"node":{"number":1,"updatedAt":"2020-01-01T00:00:00Z","commits":{"edges":[{"node":{"commit":{"oid":"oid1"}}}]}}}
+		],"pageInfo":{"endCursor":"cursor1","hasNextPage":true}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	since := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+	pulls, err := client.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, since)
+	require.NoError(t, err)
+
+	if assert.Len(t, pulls, 1) {
+		assert.Equal(t, 2, pulls[0].Number)
+	}
+	assert.Equal(t, 1, calls, "expected pagination to stop at the first older pull request")
+}
+
+func TestGithubClientPagination(t *testing.T) {
+	tests := []struct {
+		description string
+		pages       map[string]string
+		call        func(*testing.T, *resource.GithubClient)
+		wantQueries int
+		wantDeletes []string
+	}{
+		{
+			description: "list pull requests walks all labels",
+			pages: map[string]string{
+				"": `{"data":{"repository":{"pullRequests":{"edges":[
+					{"node":{"number":1,"commits":{"edges":[{"node":{"commit":{"oid":"oid1"}}}]},"labels":{"edges":[{"node":{"name":"label1"}}],"pageInfo":{"endCursor":"labels1","hasNextPage":true}}}}
+				],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`,
+				"labels1": `{"data":{"repository":{"pullRequest":{"labels":{"edges":[{"node":{"name":"label2"}}],"pageInfo":{"endCursor":"labels2","hasNextPage":true}}}}}}`,
+				"labels2": `{"data":{"repository":{"pullRequest":{"labels":{"edges":[{"node":{"name":"label3"}}],"pageInfo":{"hasNextPage":false}}}}}}`,
+			},
+			call: func(t *testing.T, c *resource.GithubClient) {
+				pulls, err := c.ListPullRequests(context.TODO(), []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+				require.NoError(t, err)
+				require.Len(t, pulls, 1)
+				assert.Equal(t, []resource.LabelObject{{Name: "label1"}, {Name: "label2"}, {Name: "label3"}}, pulls[0].Labels)
+			},
+			wantQueries: 3,
+		},
+		{
+			description: "get pull request walks commits backwards",
+			pages: map[string]string{
+				"":         `{"data":{"repository":{"pullRequest":{"number":1,"commits":{"edges":[{"node":{"commit":{"oid":"oid150"}}}],"pageInfo":{"startCursor":"commits1","hasPreviousPage":true}}}}}}`,
+				"commits1": `{"data":{"repository":{"pullRequest":{"number":1,"commits":{"edges":[{"node":{"commit":{"oid":"oid50"}}}],"pageInfo":{"startCursor":"commits2","hasPreviousPage":true}}}}}}`,
+				"commits2": `{"data":{"repository":{"pullRequest":{"number":1,"commits":{"edges":[{"node":{"commit":{"oid":"oid1"}}}],"pageInfo":{"hasPreviousPage":false}}}}}}`,
+			},
+			call: func(t *testing.T, c *resource.GithubClient) {
+				pull, err := c.GetPullRequest(context.TODO(), "1", "oid1")
+				require.NoError(t, err)
+				assert.Equal(t, "oid1", pull.Tip.OID)
+
+				_, err = c.GetPullRequest(context.TODO(), "1", "missing")
+				assert.Error(t, err)
+			},
+			wantQueries: 6,
+		},
+		{
+			description: "delete previous comments walks all comments",
+			pages: map[string]string{
+				"":          `{"data":{"viewer":{"login":"concourse"},"repository":{"pullRequest":{"id":"pr1","comments":{"edges":[{"node":{"databaseId":1,"author":{"login":"concourse"}}},{"node":{"databaseId":2,"author":{"login":"someone"}}}],"pageInfo":{"endCursor":"comments1","hasNextPage":true}}}}}}`,
+				"comments1": `{"data":{"viewer":{"login":"concourse"},"repository":{"pullRequest":{"id":"pr1","comments":{"edges":[{"node":{"databaseId":3,"author":{"login":"concourse"}}}],"pageInfo":{"hasNextPage":false}}}}}}`,
+			},
+			call: func(t *testing.T, c *resource.GithubClient) {
+				require.NoError(t, c.DeletePreviousComments(context.TODO(), "1"))
+			},
+			wantQueries: 2,
+			wantDeletes: []string{"/repos/itsdalmo/test-repository/issues/comments/1", "/repos/itsdalmo/test-repository/issues/comments/3"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var queries int
+			var deletes []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodDelete {
+					deletes = append(deletes, r.URL.Path)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				queries++
+
+				var body struct {
+					Variables map[string]interface{}
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+				// Serve the page for whichever cursor was sent.
+				var cursor string
+				for _, name := range []string{"labelsEndCursor", "commitsCursor", "commentsCursor"} {
+					if v, ok := body.Variables[name].(string); ok {
+						cursor = v
+					}
+				}
+				page, ok := tc.pages[cursor]
+				require.True(t, ok, "unexpected cursor: %q", cursor)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(page))
+			}))
+			defer server.Close()
+
+			client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V3Endpoint:  server.URL,
+				V4Endpoint:  server.URL + "/graphql",
+			})
+			require.NoError(t, err)
+
+			tc.call(t, client)
+			assert.Equal(t, tc.wantQueries, queries)
+			assert.Equal(t, tc.wantDeletes, deletes)
+		})
+	}
+}
+
+func TestGithubClientTLSAndProxy(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequests":{"edges":[],"pageInfo":{"hasNextPage":false}}},"rateLimit":{"cost":1,"remaining":4999}}}`))
+	})
+
+	t.Run("verifies tls using the ca certificate", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		source := resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+			V3Endpoint:  server.URL,
+			V4Endpoint:  server.URL + "/graphql",
+		}
+		client, err := resource.NewGithubClient(context.TODO(), &source)
+		require.NoError(t, err)
+		assert.Error(t, listPullRequests(client), "expected an unknown authority error")
+
+		source.CACert = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+		require.NoError(t, source.Validate())
+		client, err = resource.NewGithubClient(context.TODO(), &source)
+		require.NoError(t, err)
+		assert.NoError(t, listPullRequests(client))
+	})
+
+	t.Run("sends requests through the proxy", func(t *testing.T) {
+		var hosts []string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hosts = append(hosts, r.Host)
+			handler(w, r)
+		}))
+		defer proxy.Close()
+
+		client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+			V3Endpoint:  "http://github.example.com/",
+			V4Endpoint:  "http://github.example.com/graphql",
+			Proxy:       proxy.URL,
+		})
+		require.NoError(t, err)
+		assert.NoError(t, listPullRequests(client))
+		assert.Equal(t, []string{"github.example.com"}, hosts)
+	})
+}
+
+func TestGithubClientTimeouts(t *testing.T) {
+	done := make(chan struct{})
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:     "itsdalmo/test-repository",
+		AccessToken:    "oauthtoken",
+		V3Endpoint:     server.URL,
+		V4Endpoint:     server.URL + "/graphql",
+		MaxRetries:     1,
+		RequestTimeout: resource.Duration(50 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	t.Run("retries requests which time out", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		assert.Error(t, listPullRequests(client))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.ListPullRequests(ctx, []githubv4.PullRequestState{githubv4.PullRequestStateOpen}, false, time.Time{})
+		assert.Error(t, err)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestListTimelineItems(t *testing.T) {
+	var query string
+	var variables map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		query, variables = body.Query, body.Variables
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"repository":{"pullRequest":{"timelineItems":{"nodes":[
+			{"__typename":"PullRequestCommit","commit":{"oid":"oid1"}},
+			{"__typename":"LabeledEvent","createdAt":"2020-01-03T00:00:00Z","actor":{"login":"maintainer"},"label":{"name":"ok-to-test"}},
+			{"__typename":"IssueComment","databaseId":42,"createdAt":"2020-01-04T00:00:00Z","author":{"login":"someone"},"authorAssociation":"NONE","body":"/ok-to-test"},
+			{"__typename":"HeadRefForcePushedEvent","createdAt":"2020-01-05T00:00:00Z","actor":{"login":"someone"},"afterCommit":{"oid":"oid3"}}
+		],"pageInfo":{"hasNextPage":false}}}}}}`))
+	}))
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(context.TODO(), &resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL + "/graphql",
+	})
+	require.NoError(t, err)
+
+	items, err := client.ListTimelineItems(context.TODO(), "1")
+	require.NoError(t, err)
+
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, []resource.TimelineItem{
+		{Type: githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, Commit: "oid1"},
+		{Type: githubv4.PullRequestTimelineItemsItemTypeLabeledEvent, CreatedAt: day(3), Actor: "maintainer", Label: "ok-to-test"},
+		{Type: githubv4.PullRequestTimelineItemsItemTypeIssueComment, CreatedAt: day(4), Actor: "someone", AuthorAssociation: githubv4.CommentAuthorAssociationNone, Body: "/ok-to-test", CommentID: 42},
+		{Type: githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent, CreatedAt: day(5), Actor: "someone", Commit: "oid3"},
+	}, items)
+	assert.Equal(t, []interface{}{"LABELED_EVENT", "ISSUE_COMMENT", "PULL_REQUEST_COMMIT", "HEAD_REF_FORCE_PUSHED_EVENT"}, variables["timelineTypes"])
+	assert.NotContains(t, query, "pushedDate")
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,32 +11,39 @@ import (
 )
 
 // Get (business logic)
-func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResponse, error) {
+func Get(ctx context.Context, request GetRequest, github Github, git Git, outputDir string) (*GetResponse, error) {
 	if request.Params.SkipDownload {
 		return &GetResponse{Version: request.Version}, nil
 	}
 
-	pull, err := github.GetPullRequest(request.Version.PR, request.Version.Commit)
+	pull, err := github.GetPullRequest(ctx, request.Version.PR, request.Version.Commit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve pull request: %s", err)
 	}
 
 	// Initialize and pull the base for the PR
-	if err := git.Init(pull.BaseRefName); err != nil {
+	if err := git.Init(ctx, pull.BaseRefName); err != nil {
 		return nil, err
 	}
-	if err := git.Pull(pull.Repository.URL, pull.BaseRefName, request.Params.GitDepth, request.Params.Submodules, request.Params.FetchTags); err != nil {
+	if err := git.Pull(ctx, pull.Repository.URL, pull.BaseRefName, request.Params.GitDepth, request.Params.Submodules, request.Params.FetchTags); err != nil {
 		return nil, err
 	}
 
+	// Pin the base to the commit recorded by trigger_on_base_change
+	if request.Version.BaseCommit != "" {
+		if err := git.Reset(ctx, request.Version.BaseCommit, request.Params.GitDepth, request.Params.Submodules); err != nil {
+			return nil, err
+		}
+	}
+
 	// Get the last commit SHA in base for the metadata
-	baseSHA, err := git.RevParse(pull.BaseRefName)
+	baseSHA, err := git.RevParse(ctx, pull.BaseRefName)
 	if err != nil {
 		return nil, err
 	}
 
 	// Fetch the PR and merge the specified commit into the base
-	if err := git.Fetch(pull.Repository.URL, pull.Number, request.Params.GitDepth, request.Params.Submodules); err != nil {
+	if err := git.Fetch(ctx, pull.Repository.URL, pull.Number, request.Params.GitDepth, request.Params.Submodules); err != nil {
 		return nil, err
 	}
 
@@ -83,15 +91,15 @@ func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResp
 
 	switch tool := request.Params.IntegrationTool; tool {
 	case "rebase":
-		if err := git.Rebase(pull.BaseRefName, pull.Tip.OID, request.Params.Submodules); err != nil {
+		if err := git.Rebase(ctx, pull.BaseRefName, pull.Tip.OID, request.Params.Submodules); err != nil {
 			return nil, err
 		}
 	case "merge", "":
-		if err := git.Merge(pull.Tip.OID, request.Params.Submodules); err != nil {
+		if err := git.Merge(ctx, pull.Tip.OID, request.Params.Submodules); err != nil {
 			return nil, err
 		}
 	case "checkout":
-		if err := git.Checkout(pull.HeadRefName, pull.Tip.OID, request.Params.Submodules); err != nil {
+		if err := git.Checkout(ctx, pull.HeadRefName, pull.Tip.OID, request.Params.Submodules); err != nil {
 			return nil, err
 		}
 	default:
@@ -99,13 +107,13 @@ func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResp
 	}
 
 	if request.Source.GitCryptKey != "" {
-		if err := git.GitCryptUnlock(request.Source.GitCryptKey); err != nil {
+		if err := git.GitCryptUnlock(ctx, request.Source.GitCryptKey); err != nil {
 			return nil, err
 		}
 	}
 
 	if request.Params.ListChangedFiles {
-		cfol, err := github.GetChangedFiles(request.Version.PR, request.Version.Commit)
+		cfol, err := github.GetChangedFiles(ctx, request.Version.PR, request.Version.Commit)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch list of changed files: %s", err)
 		}
@@ -1,6 +1,7 @@
 package resource_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -154,6 +155,28 @@ func TestGet(t *testing.T) {
 			metadataString: `[{"name":"pr","value":"1"},{"name":"title","value":"pr1 title"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"author_email","value":"user@example.com"},{"name":"state","value":"OPEN"}]`,
 			filesString:    "README.md\nOther.md\n",
 		},
+		{
+			description: "get pins the base to the commit in the version",
+			source: resource.Source{
+				Repository:          "itsdalmo/test-repository",
+				AccessToken:         "oauthtoken",
+				TriggerOnBaseChange: true,
+			},
+			version: resource.Version{
+				PR:                  "pr1",
+				Commit:              "commit1",
+				CommittedDate:       time.Time{},
+				ApprovedReviewCount: "0",
+				State:               githubv4.PullRequestStateOpen,
+				BaseCommit:          "base1",
+			},
+			parameters: resource.GetParameters{
+				GitDepth: 2,
+			},
+			pullRequest:    createTestPR(1, "master", false, false, 0, nil, false, githubv4.PullRequestStateOpen),
+			versionString:  `{"pr":"pr1","commit":"commit1","committed":"0001-01-01T00:00:00Z","approved_review_count":"0","state":"OPEN","base_commit":"base1"}`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"title","value":"pr1 title"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"author_email","value":"user@example.com"},{"name":"state","value":"OPEN"}]`,
+		},
 	}
 
 	for _, tc := range tests {
@@ -172,7 +195,7 @@ func TestGet(t *testing.T) {
 			defer os.RemoveAll(dir)
 
 			input := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.parameters}
-			output, err := resource.Get(input, github, git, dir)
+			output, err := resource.Get(context.TODO(), input, github, git, dir)
 
 			// Validate output
 			if assert.NoError(t, err) {
@@ -212,19 +235,19 @@ func TestGet(t *testing.T) {
 
 			// Validate Github calls
 			if assert.Equal(t, 1, github.GetPullRequestCallCount()) {
-				pr, commit := github.GetPullRequestArgsForCall(0)
+				_, pr, commit := github.GetPullRequestArgsForCall(0)
 				assert.Equal(t, tc.version.PR, pr)
 				assert.Equal(t, tc.version.Commit, commit)
 			}
 
 			// Validate Git calls
 			if assert.Equal(t, 1, git.InitCallCount()) {
-				base := git.InitArgsForCall(0)
+				_, base := git.InitArgsForCall(0)
 				assert.Equal(t, tc.pullRequest.BaseRefName, base)
 			}
 
 			if assert.Equal(t, 1, git.PullCallCount()) {
-				url, base, depth, submodules, fetchTags := git.PullArgsForCall(0)
+				_, url, base, depth, submodules, fetchTags := git.PullArgsForCall(0)
 				assert.Equal(t, tc.pullRequest.Repository.URL, url)
 				assert.Equal(t, tc.pullRequest.BaseRefName, base)
 				assert.Equal(t, tc.parameters.GitDepth, depth)
@@ -232,13 +255,22 @@ func TestGet(t *testing.T) {
 				assert.Equal(t, tc.parameters.FetchTags, fetchTags)
 			}
 
+			if tc.version.BaseCommit == "" {
+				assert.Equal(t, 0, git.ResetCallCount())
+			} else if assert.Equal(t, 1, git.ResetCallCount()) {
+				_, sha, depth, submodules := git.ResetArgsForCall(0)
+				assert.Equal(t, tc.version.BaseCommit, sha)
+				assert.Equal(t, tc.parameters.GitDepth, depth)
+				assert.Equal(t, tc.parameters.Submodules, submodules)
+			}
+
 			if assert.Equal(t, 1, git.RevParseCallCount()) {
-				base := git.RevParseArgsForCall(0)
+				_, base := git.RevParseArgsForCall(0)
 				assert.Equal(t, tc.pullRequest.BaseRefName, base)
 			}
 
 			if assert.Equal(t, 1, git.FetchCallCount()) {
-				url, pr, depth, submodules := git.FetchArgsForCall(0)
+				_, url, pr, depth, submodules := git.FetchArgsForCall(0)
 				assert.Equal(t, tc.pullRequest.Repository.URL, url)
 				assert.Equal(t, tc.pullRequest.Number, pr)
 				assert.Equal(t, tc.parameters.GitDepth, depth)
@@ -248,28 +280,28 @@ func TestGet(t *testing.T) {
 			switch tc.parameters.IntegrationTool {
 			case "rebase":
 				if assert.Equal(t, 1, git.RebaseCallCount()) {
-					branch, tip, submodules := git.RebaseArgsForCall(0)
+					_, branch, tip, submodules := git.RebaseArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.BaseRefName, branch)
 					assert.Equal(t, tc.pullRequest.Tip.OID, tip)
 					assert.Equal(t, tc.parameters.Submodules, submodules)
 				}
 			case "checkout":
 				if assert.Equal(t, 1, git.CheckoutCallCount()) {
-					branch, sha, submodules := git.CheckoutArgsForCall(0)
+					_, branch, sha, submodules := git.CheckoutArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.HeadRefName, branch)
 					assert.Equal(t, tc.pullRequest.Tip.OID, sha)
 					assert.Equal(t, tc.parameters.Submodules, submodules)
 				}
 			default:
 				if assert.Equal(t, 1, git.MergeCallCount()) {
-					tip, submodules := git.MergeArgsForCall(0)
+					_, tip, submodules := git.MergeArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.Tip.OID, tip)
 					assert.Equal(t, tc.parameters.Submodules, submodules)
 				}
 			}
 			if tc.source.GitCryptKey != "" {
 				if assert.Equal(t, 1, git.GitCryptUnlockCallCount()) {
-					key := git.GitCryptUnlockArgsForCall(0)
+					_, key := git.GitCryptUnlockArgsForCall(0)
 					assert.Equal(t, tc.source.GitCryptKey, key)
 				}
 			}
@@ -309,7 +341,7 @@ func TestGetSkipDownload(t *testing.T) {
 
 			// Run the get and check output
 			input := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.parameters}
-			output, err := resource.Get(input, github, git, dir)
+			output, err := resource.Get(context.TODO(), input, github, git, dir)
 
 			if assert.NoError(t, err) {
 				assert.Equal(t, tc.version, output.Version)
@@ -1,47 +1,144 @@
 package resource
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"path"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/shurcooL/githubv4"
 )
 
+// Forges which are supported by the resource.
+const (
+	ForgeGithub = "github"
+	ForgeGitea  = "gitea"
+)
+
+// Modes for matching the labels in the source.
+const (
+	LabelMatchAny = "any"
+	LabelMatchAll = "all"
+)
+
 // Source represents the configuration for the resource.
 type Source struct {
-	Repository              string                      `json:"repository"`
-	AccessToken             string                      `json:"access_token"`
-	V3Endpoint              string                      `json:"v3_endpoint"`
-	V4Endpoint              string                      `json:"v4_endpoint"`
-	Paths                   []string                    `json:"paths"`
-	IgnorePaths             []string                    `json:"ignore_paths"`
-	DisableCISkip           bool                        `json:"disable_ci_skip"`
-	DisableGitLFS           bool                        `json:"disable_git_lfs"`
-	SkipSSLVerification     bool                        `json:"skip_ssl_verification"`
-	DisableForks            bool                        `json:"disable_forks"`
-	IgnoreDrafts            bool                        `json:"ignore_drafts"`
-	GitCryptKey             string                      `json:"git_crypt_key"`
-	BaseBranch              string                      `json:"base_branch"`
-	RequiredReviewApprovals int                         `json:"required_review_approvals"`
-	Labels                  []string                    `json:"labels"`
-	States                  []githubv4.PullRequestState `json:"states"`
+	Forge                   string                              `json:"forge"`
+	Repository              string                              `json:"repository"`
+	AccessToken             string                              `json:"access_token"`
+	AppID                   int64                               `json:"app_id"`
+	InstallationID          int64                               `json:"installation_id"`
+	PrivateKey              string                              `json:"private_key"`
+	V3Endpoint              string                              `json:"v3_endpoint"`
+	V4Endpoint              string                              `json:"v4_endpoint"`
+	Paths                   []string                            `json:"paths"`
+	IgnorePaths             []string                            `json:"ignore_paths"`
+	DisableCISkip           bool                                `json:"disable_ci_skip"`
+	SkipCIPatterns          []string                            `json:"skip_ci_patterns"`
+	SkipCIBody              bool                                `json:"skip_ci_body"`
+	SkipCIAllCommits        bool                                `json:"skip_ci_all_commits"`
+	EveryCommit             bool                                `json:"every_commit"`
+	DisableGitLFS           bool                                `json:"disable_git_lfs"`
+	SkipSSLVerification     bool                                `json:"skip_ssl_verification"`
+	CACert                  string                              `json:"ca_cert"`
+	Proxy                   string                              `json:"proxy"`
+	NoProxy                 string                              `json:"no_proxy"`
+	DisableForks            bool                                `json:"disable_forks"`
+	IgnoreDrafts            bool                                `json:"ignore_drafts"`
+	GitCryptKey             string                              `json:"git_crypt_key"`
+	BaseBranch              StringList                          `json:"base_branch"`
+	BaseBranchRegex         string                              `json:"base_branch_regex"`
+	IgnoreBaseBranchRegex   string                              `json:"ignore_base_branch_regex"`
+	HeadBranch              string                              `json:"head_branch"`
+	IgnoreHeadBranch        string                              `json:"ignore_head_branch"`
+	TitleRegex              string                              `json:"title_regex"`
+	IgnoreTitleRegex        string                              `json:"ignore_title_regex"`
+	BodyRegex               string                              `json:"body_regex"`
+	IgnoreBodyRegex         string                              `json:"ignore_body_regex"`
+	RequiredReviewApprovals int                                 `json:"required_review_approvals"`
+	RequireReviewDecision   bool                                `json:"require_review_decision"`
+	BlockChangesRequested   bool                                `json:"block_changes_requested"`
+	RequiredStatuses        []string                            `json:"required_statuses"`
+	RequiredCheckRuns       []string                            `json:"required_check_runs"`
+	IgnoreConflicting       bool                                `json:"ignore_conflicting"`
+	RequireUpToDate         bool                                `json:"require_up_to_date"`
+	Labels                  []string                            `json:"labels"`
+	IgnoreLabels            []string                            `json:"ignore_labels"`
+	LabelMatch              string                              `json:"label_match"`
+	States                  []githubv4.PullRequestState         `json:"states"`
+	AllowedAuthors          []string                            `json:"allowed_authors"`
+	IgnoredAuthors          []string                            `json:"ignored_authors"`
+	AuthorAssociations      []githubv4.CommentAuthorAssociation `json:"author_associations"`
+	OkToTestLabel           string                              `json:"ok_to_test_label"`
+	OkToTestComment         string                              `json:"ok_to_test_comment"`
+	CommentTrigger          string                              `json:"comment_trigger"`
+	TriggerLabels           []string                            `json:"trigger_labels"`
+	TriggerOnBaseChange     bool                                `json:"trigger_on_base_change"`
+	MaxRetries              int                                 `json:"max_retries"`
+	MaxRetryWait            Duration                            `json:"max_retry_wait"`
+	RequestTimeout          Duration                            `json:"request_timeout"`
+	GitTimeout              Duration                            `json:"git_timeout"`
+	Debug                   bool                                `json:"debug"`
+	Explain                 bool                                `json:"explain"`
 }
 
 // Validate the source configuration.
 func (s *Source) Validate() error {
-	if s.AccessToken == "" {
-		return errors.New("access_token must be set")
+	if s.AccessToken == "" && !s.useGithubApp() {
+		return errors.New("either access_token or app_id, installation_id and private_key must be set")
+	}
+	if s.AccessToken != "" && s.useGithubApp() {
+		return errors.New("access_token cannot be set together with app_id, installation_id or private_key")
+	}
+	if s.useGithubApp() && (s.AppID == 0 || s.InstallationID == 0 || s.PrivateKey == "") {
+		return errors.New("app_id, installation_id and private_key must be set together")
 	}
 	if s.Repository == "" {
 		return errors.New("repository must be set")
 	}
-	if s.V3Endpoint != "" && s.V4Endpoint == "" {
-		return errors.New("v4_endpoint must be set together with v3_endpoint")
+	switch s.Forge {
+	case "", ForgeGithub:
+		if s.V3Endpoint != "" && s.V4Endpoint == "" {
+			return errors.New("v4_endpoint must be set together with v3_endpoint")
+		}
+		if s.V4Endpoint != "" && s.V3Endpoint == "" {
+			return errors.New("v3_endpoint must be set together with v4_endpoint")
+		}
+	case ForgeGitea:
+		if s.useGithubApp() {
+			return errors.New("gitea only supports authentication with access_token")
+		}
+		if s.V3Endpoint == "" {
+			return errors.New("v3_endpoint must be set to the gitea api (e.g. https://gitea.example.com/api/v1)")
+		}
+		if len(s.AuthorAssociations) > 0 {
+			return errors.New("author_associations is not supported by gitea")
+		}
+		if len(s.RequiredCheckRuns) > 0 {
+			return errors.New("required_check_runs is not supported by gitea (use required_statuses)")
+		}
+		if s.RequireUpToDate {
+			return errors.New("require_up_to_date is not supported by gitea")
+		}
+	default:
+		return fmt.Errorf("forge \"%s\" must be one of: %s, %s", s.Forge, ForgeGithub, ForgeGitea)
+	}
+	if s.CACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(s.CACert)) {
+		return errors.New("ca_cert must contain at least one PEM encoded certificate")
+	}
+	if s.Proxy != "" {
+		if _, err := url.Parse(s.Proxy); err != nil {
+			return fmt.Errorf("failed to parse proxy: %s", err)
+		}
 	}
-	if s.V4Endpoint != "" && s.V3Endpoint == "" {
-		return errors.New("v3_endpoint must be set together with v4_endpoint")
+	if s.MaxRetries < -1 {
+		return errors.New("max_retries must be -1 (disabled) or greater")
 	}
 	for _, state := range s.States {
 		switch state {
@@ -52,6 +149,155 @@ func (s *Source) Validate() error {
 			return errors.New(fmt.Sprintf("states value \"%s\" must be one of: OPEN, MERGED, CLOSED", state))
 		}
 	}
+	if _, err := NewPathFilter(s.PathPatterns()); err != nil {
+		return err
+	}
+	if _, err := s.regexps(); err != nil {
+		return err
+	}
+	for _, pattern := range s.BaseBranch {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid base_branch pattern \"%s\": %s", pattern, err)
+		}
+	}
+	for _, pattern := range append(append(append([]string{}, s.Labels...), s.IgnoreLabels...), s.TriggerLabels...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid label pattern \"%s\": %s", pattern, err)
+		}
+	}
+	switch s.LabelMatch {
+	case "", LabelMatchAny, LabelMatchAll:
+	default:
+		return fmt.Errorf("label_match \"%s\" must be one of: %s, %s", s.LabelMatch, LabelMatchAny, LabelMatchAll)
+	}
+	for _, association := range s.AuthorAssociations {
+		switch association {
+		case githubv4.CommentAuthorAssociationOwner:
+		case githubv4.CommentAuthorAssociationMember:
+		case githubv4.CommentAuthorAssociationCollaborator:
+		case githubv4.CommentAuthorAssociationContributor:
+		case githubv4.CommentAuthorAssociationFirstTimeContributor:
+		case githubv4.CommentAuthorAssociationFirstTimer:
+		case githubv4.CommentAuthorAssociationNone:
+		default:
+			return fmt.Errorf("author_associations value \"%s\" must be one of: OWNER, MEMBER, COLLABORATOR, CONTRIBUTOR, FIRST_TIME_CONTRIBUTOR, FIRST_TIMER, NONE", association)
+		}
+	}
+	return nil
+}
+
+// PathPatterns returns the ordered path patterns of the source. For compatibility,
+// patterns which contain no "/" (other than a trailing one) are anchored to the
+// root so that they keep matching the same files as before, and ignore_paths are
+// added as negated patterns after paths (which default to all files when only
+// ignore_paths are set).
+func (s *Source) PathPatterns() []string {
+	var patterns []string
+	for _, p := range s.Paths {
+		if strings.HasPrefix(p, "!") {
+			patterns = append(patterns, "!"+anchorPattern(p[1:]))
+			continue
+		}
+		patterns = append(patterns, anchorPattern(p))
+	}
+	if len(s.IgnorePaths) > 0 && len(patterns) == 0 {
+		patterns = append(patterns, "**")
+	}
+	for _, p := range s.IgnorePaths {
+		patterns = append(patterns, "!"+anchorPattern(p))
+	}
+	return patterns
+}
+
+// anchorPattern anchors a path pattern to the root if it contains no "/" (other than a trailing one).
+func anchorPattern(p string) string {
+	if strings.Contains(strings.TrimSuffix(p, "/"), "/") {
+		return p
+	}
+	return "/" + p
+}
+
+// regexps compiles the regular expressions of the source, keyed by the name of
+// their field. Fields which are not set are left out.
+func (s *Source) regexps() (map[string]*regexp.Regexp, error) {
+	regexps := make(map[string]*regexp.Regexp)
+	for name, pattern := range map[string]string{
+		"base_branch_regex":        s.BaseBranchRegex,
+		"ignore_base_branch_regex": s.IgnoreBaseBranchRegex,
+		"head_branch":              s.HeadBranch,
+		"ignore_head_branch":       s.IgnoreHeadBranch,
+		"title_regex":              s.TitleRegex,
+		"ignore_title_regex":       s.IgnoreTitleRegex,
+		"body_regex":               s.BodyRegex,
+		"ignore_body_regex":        s.IgnoreBodyRegex,
+		"comment_trigger":          s.CommentTrigger,
+	} {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile %s: %s", name, err)
+		}
+		regexps[name] = re
+	}
+	return regexps, nil
+}
+
+// skipCIPatterns returns the patterns which mark a pull request or commit as
+// skipped, which default to [ci skip] and [skip ci].
+func (s *Source) skipCIPatterns() []string {
+	if len(s.SkipCIPatterns) > 0 {
+		return s.SkipCIPatterns
+	}
+	return defaultSkipCIPatterns
+}
+
+// useGithubApp returns true if any of the Github App credentials are set.
+func (s *Source) useGithubApp() bool {
+	return s.AppID != 0 || s.InstallationID != 0 || s.PrivateKey != ""
+}
+
+// Duration is a time.Duration which is configured as a string (e.g. "30s").
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %s", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// StringList is a list of strings which can also be configured as a single string.
+type StringList []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*l = nil
+		if s != "" {
+			*l = StringList{s}
+		}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("must be a string or a list of strings: %s", err)
+	}
+	*l = list
 	return nil
 }
 
@@ -76,25 +322,37 @@ type Version struct {
 	CommittedDate       time.Time                 `json:"committed,omitempty"`
 	ApprovedReviewCount string                    `json:"approved_review_count"`
 	State               githubv4.PullRequestState `json:"state"`
+	CommentID           string                    `json:"comment_id,omitempty"`
+	CommentAuthor       string                    `json:"comment_author,omitempty"`
+	BaseCommit          string                    `json:"base_commit,omitempty"`
+	PushedDate          *time.Time                `json:"pushed,omitempty"`
+	Conflicting         string                    `json:"conflicting,omitempty"` // Pull requests rejected by ignore_conflicting (e.g. "3,7").
 }
 
 // NewVersion constructs a new Version.
 func NewVersion(p *PullRequest) Version {
+	pushed := p.PushedDate(p.Tip)
 	return Version{
 		PR:                  strconv.Itoa(p.Number),
 		Commit:              p.Tip.OID,
 		CommittedDate:       p.UpdatedDate().Time,
 		ApprovedReviewCount: strconv.Itoa(p.ApprovedReviewCount),
 		State:               p.State,
+		PushedDate:          &pushed,
 	}
 }
 
 // PullRequest represents a pull request and includes the tip (commit).
 type PullRequest struct {
 	PullRequestObject
-	Tip                 CommitObject
-	ApprovedReviewCount int
-	Labels              []LabelObject
+	Tip                   CommitObject
+	ApprovedReviewCount   int
+	ChangesRequestedCount int
+	Labels                []LabelObject
+	Files                 []ChangedFileObject
+	Statuses              []StatusObject
+	CheckRuns             []StatusObject
+	TimelineItems         []TimelineItem // Recent comments and labels since the current version, and the last force push.
 }
 
 // PullRequestObject represents the GraphQL commit node.
@@ -103,23 +361,40 @@ type PullRequestObject struct {
 	ID          string
 	Number      int
 	Title       string
+	Body        string
 	URL         string
 	BaseRefName string
+	BaseRef     struct {
+		Target struct {
+			OID    string
+			Commit struct {
+				CommittedDate githubv4.DateTime
+			} `graphql:"... on Commit"`
+		}
+	}
 	HeadRefName string
 	Repository  struct {
 		URL string
 	}
+	Author struct {
+		Login string
+	}
+	AuthorAssociation githubv4.CommentAuthorAssociation
 	IsCrossRepository bool
 	IsDraft           bool
+	ReviewDecision    githubv4.PullRequestReviewDecision
+	Mergeable         githubv4.MergeableState
+	MergeStateStatus  string // Schema preview (e.g. CLEAN or BEHIND), not in githubv4.
 	State             githubv4.PullRequestState
 	ClosedAt          githubv4.DateTime
 	MergedAt          githubv4.DateTime
+	UpdatedAt         githubv4.DateTime
 }
 
-// UpdatedDate returns the last time a PR was updated, either by commit
-// or being closed/merged.
+// UpdatedDate returns the last time a PR was updated, either by pushing
+// the tip or being closed/merged.
 func (p *PullRequest) UpdatedDate() githubv4.DateTime {
-	date := p.Tip.CommittedDate
+	date := githubv4.DateTime{Time: p.PushedDate(p.Tip)}
 	switch p.State {
 	case githubv4.PullRequestStateClosed:
 		date = p.ClosedAt
@@ -144,14 +419,69 @@ type CommitObject struct {
 	}
 }
 
+// ReviewObject represents the GraphQL pull request review node.
+// https://developer.github.com/v4/object/pullrequestreview/
+type ReviewObject struct {
+	Author struct {
+		Login string
+	}
+	State  githubv4.PullRequestReviewState
+	Commit struct {
+		OID string
+	}
+}
+
+// StatusObject represents a status context or check run of a commit.
+// https://developer.github.com/v4/object/statuscheckrollup/
+type StatusObject struct {
+	Name string
+	// State of a status context, or the conclusion of a completed check run (its status otherwise).
+	State string
+	// CompletedAt is when the status was set or the check run completed (zero until then).
+	CompletedAt time.Time
+}
+
 // ChangedFileObject represents the GraphQL FilesChanged node.
 // https://developer.github.com/v4/object/pullrequestchangedfile/
 type ChangedFileObject struct {
 	Path string
 }
 
+// PushedDate returns the last time a commit was pushed to the PR according to
+// the timeline items (e.g. the last force push), or when it was committed if
+// the push is not among them.
+// Force pushes are always timed, while regular pushes are only timed by Gitea.
+func (p *PullRequest) PushedDate(c CommitObject) time.Time {
+	var pushed time.Time
+	for _, item := range p.TimelineItems {
+		switch item.Type {
+		case githubv4.PullRequestTimelineItemsItemTypePullRequestCommit, githubv4.PullRequestTimelineItemsItemTypeHeadRefForcePushedEvent:
+			if item.Commit == c.OID && item.CreatedAt.After(pushed) {
+				pushed = item.CreatedAt
+			}
+		}
+	}
+	if pushed.IsZero() {
+		return c.CommittedDate.Time
+	}
+	return pushed
+}
+
 // LabelObject represents the GraphQL label node.
 // https://developer.github.com/v4/object/label
 type LabelObject struct {
 	Name string
 }
+
+// TimelineItem represents a label, comment or push on the timeline of a pull request.
+// https://developer.github.com/v4/union/pullrequesttimelineitems/
+type TimelineItem struct {
+	Type              githubv4.PullRequestTimelineItemsItemType
+	CreatedAt         time.Time // Zero for regular pushes to Github, which are not timed.
+	Actor             string
+	AuthorAssociation githubv4.CommentAuthorAssociation
+	Label             string
+	Body              string
+	CommentID         int64
+	Commit            string
+}
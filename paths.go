@@ -0,0 +1,139 @@
+package resource
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathFilter selects files using an ordered list of gitignore-style patterns,
+// where later patterns take precedence over earlier ones:
+//
+// - `*` and `?` do not match `/`, while `**` matches any number of directories.
+// - Patterns containing a `/` (other than a trailing one) are relative to the root
+//   of the repository, other patterns match at any depth.
+// - Patterns ending with `/` only match directories, and a pattern which matches a
+//   directory matches everything inside it.
+// - Patterns starting with `!` deselect files selected by an earlier pattern.
+type PathFilter struct {
+	patterns []pathPattern
+}
+
+type pathPattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewPathFilter compiles the patterns of a path filter.
+func NewPathFilter(patterns []string) (*PathFilter, error) {
+	f := &PathFilter{}
+	for _, p := range patterns {
+		pattern, err := compilePathPattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern \"%s\": %s", p, err)
+		}
+		f.patterns = append(f.patterns, pattern)
+	}
+	return f, nil
+}
+
+// Match returns true if the file is selected by the filter.
+func (f *PathFilter) Match(file string) bool {
+	selected := false
+	for _, p := range f.patterns {
+		if p.negate == selected && p.match(file) {
+			selected = !p.negate
+		}
+	}
+	return selected
+}
+
+// Filter returns the files which are selected by the filter.
+func (f *PathFilter) Filter(files []string) []string {
+	var out []string
+	for _, file := range files {
+		if f.Match(file) {
+			out = append(out, file)
+		}
+	}
+	return out
+}
+
+// match returns true if the pattern matches the file or one of its parent directories.
+func (p pathPattern) match(file string) bool {
+	file = strings.Trim(file, "/")
+	for i := 0; i < len(file); i++ {
+		if file[i] == '/' && p.re.MatchString(file[:i]) {
+			return true
+		}
+	}
+	return !p.dirOnly && p.re.MatchString(file)
+}
+
+func compilePathPattern(p string) (pathPattern, error) {
+	var pattern pathPattern
+	if strings.HasPrefix(p, "!") {
+		pattern.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		pattern.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return pattern, fmt.Errorf("empty pattern")
+	}
+
+	// Patterns without a slash match at any depth, others are relative to the root.
+	prefix := "^(?:.*/)?"
+	if strings.Contains(p, "/") {
+		prefix = "^"
+		p = strings.TrimPrefix(p, "/")
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '*' && strings.HasPrefix(p[i:], "**/") && (i == 0 || p[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && p[i:] == "**" && (i == 0 || p[i-1] == '/'):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return pattern, err
+	}
+	pattern.re = re
+	return pattern, nil
+}
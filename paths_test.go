@@ -0,0 +1,140 @@
+package resource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestPathFilter(t *testing.T) {
+	files := []string{
+		"README.md",
+		"docs/index.md",
+		"services/api/main.go",
+		"services/api/docs/api.md",
+		"services/web/docs/web.md",
+		"services/web/index.js",
+		"terraform/modules/vpc/main.tf",
+		"terraform/main.tf",
+	}
+
+	tests := []struct {
+		description string
+		patterns    []string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			description: "patterns without a slash match at any depth",
+			patterns:    []string{"*.md"},
+			want:        []string{"README.md", "docs/index.md", "services/api/docs/api.md", "services/web/docs/web.md"},
+		},
+		{
+			description: "patterns with a slash are relative to the root",
+			patterns:    []string{"terraform/*.tf"},
+			want:        []string{"terraform/main.tf"},
+		},
+		{
+			description: "double star matches any number of directories",
+			patterns:    []string{"terraform/**/*.tf"},
+			want:        []string{"terraform/modules/vpc/main.tf", "terraform/main.tf"},
+		},
+		{
+			description: "leading double star matches in all directories",
+			patterns:    []string{"**/docs/*.md"},
+			want:        []string{"docs/index.md", "services/api/docs/api.md", "services/web/docs/web.md"},
+		},
+		{
+			description: "trailing slash matches everything in a directory",
+			patterns:    []string{"docs/"},
+			want:        []string{"docs/index.md", "services/api/docs/api.md", "services/web/docs/web.md"},
+		},
+		{
+			description: "trailing slash does not match files",
+			patterns:    []string{"README.md/"},
+		},
+		{
+			description: "directories match everything inside them",
+			patterns:    []string{"services/api"},
+			want:        []string{"services/api/main.go", "services/api/docs/api.md"},
+		},
+		{
+			description: "negation deselects earlier matches",
+			patterns:    []string{"services/", "!docs/"},
+			want:        []string{"services/api/main.go", "services/web/index.js"},
+		},
+		{
+			description: "later patterns take precedence",
+			patterns:    []string{"services/", "!docs/", "services/web/docs/"},
+			want:        []string{"services/api/main.go", "services/web/docs/web.md", "services/web/index.js"},
+		},
+		{
+			description: "character classes are supported",
+			patterns:    []string{"services/[!a]*/index.js"},
+			want:        []string{"services/web/index.js"},
+		},
+		{
+			description: "escaped negation is a literal",
+			patterns:    []string{`\!important`},
+		},
+		{
+			description: "empty patterns are invalid",
+			patterns:    []string{"!"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			filter, err := resource.NewPathFilter(tc.patterns)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, filter.Filter(files))
+		})
+	}
+}
+
+func TestSourcePathPatterns(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		want        []string
+	}{
+		{
+			description: "returns paths with a slash as is",
+			source:      resource.Source{Paths: []string{"services/api/", "!**/docs/", "/README.md"}},
+			want:        []string{"services/api/", "!**/docs/", "/README.md"},
+		},
+		{
+			description: "anchors paths without a slash to the root",
+			source:      resource.Source{Paths: []string{"services/", "*.md", "!README.md"}},
+			want:        []string{"/services/", "/*.md", "!/README.md"},
+		},
+		{
+			description: "adds ignore_paths as negations",
+			source:      resource.Source{Paths: []string{"services/"}, IgnorePaths: []string{"*.md"}},
+			want:        []string{"/services/", "!/*.md"},
+		},
+		{
+			description: "selects all files when only ignore_paths is set",
+			source:      resource.Source{IgnorePaths: []string{".ci/"}},
+			want:        []string{"**", "!/.ci/"},
+		},
+		{
+			description: "keeps ignore_paths with a slash as is",
+			source:      resource.Source{IgnorePaths: []string{"docs/*.md", "/README.md"}},
+			want:        []string{"**", "!docs/*.md", "!/README.md"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.source.PathPatterns())
+		})
+	}
+}
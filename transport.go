@@ -0,0 +1,262 @@
+package resource
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultMaxRetryWait   = time.Minute
+	defaultRequestTimeout = time.Minute
+	retryBaseDelay        = time.Second
+
+	// mergeInfoPreview enables mergeStateStatus in the GraphQL API.
+	mergeInfoPreview = "application/vnd.github.merge-info-preview+json"
+)
+
+// newTransport returns the base transport for requests to the Github APIs,
+// configured with the TLS and proxy settings from the source.
+func newTransport(s *Source) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if s.SkipSSLVerification {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	} else if s.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(s.CACert)) {
+			return nil, errors.New("failed to parse ca_cert")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if s.Proxy != "" || s.NoProxy != "" {
+		config := httpproxy.FromEnvironment()
+		if s.Proxy != "" {
+			config.HTTPProxy = s.Proxy
+			config.HTTPSProxy = s.Proxy
+		}
+		if s.NoProxy != "" {
+			config.NoProxy = s.NoProxy
+		}
+		proxy := config.ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+	return transport, nil
+}
+
+// retryTransport retries idempotent requests to the Github APIs when they
+// fail due to rate limiting, timeouts or transient server errors.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+	timeout    time.Duration
+}
+
+func newRetryTransport(base http.RoundTripper, maxRetries int, maxWait, timeout time.Duration) *retryTransport {
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if maxWait == 0 {
+		maxWait = defaultMaxRetryWait
+	}
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+	return &retryTransport{
+		base:       base,
+		maxRetries: maxRetries,
+		maxWait:    maxWait,
+		timeout:    timeout,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		// Each attempt gets its own deadline, which lasts until the body is closed.
+		ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+		r := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		start := time.Now()
+		resp, err := t.base.RoundTrip(r)
+		fields := Fields{"method": req.Method, "url": req.URL.String(), "attempt": attempt + 1, "duration_ms": milliseconds(start)}
+		if err != nil {
+			cancel()
+			fields["error"] = err.Error()
+		} else {
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			fields["status"] = resp.StatusCode
+		}
+		LoggerFromContext(req.Context()).Debug("http request", fields)
+		logRateLimit(resp)
+		if !retryable || attempt >= t.maxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait, ok := retryDelay(attempt, resp, err)
+		if !ok || wait > t.maxWait {
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		log.Printf("retrying %s %s in %s (attempt %d/%d): %s", req.Method, req.URL.Path, wait.Round(time.Millisecond), attempt+1, t.maxRetries, reason)
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// acceptTransport sets the Accept header of requests, e.g. to opt in to
+// GraphQL schema previews.
+type acceptTransport struct {
+	base   http.RoundTripper
+	accept string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *acceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Header.Set("Accept", t.accept)
+	return t.base.RoundTrip(r)
+}
+
+// retryDelay returns how long to wait before retrying, and false if the
+// response should not be retried at all.
+func retryDelay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		// Only network errors and timeouts are transient, e.g. not certificate errors.
+		var netErr net.Error
+		return backoff(attempt), errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff(attempt), true
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		// Secondary rate limits come with a Retry-After header.
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if seconds, err := strconv.Atoi(v); err == nil {
+				return time.Duration(seconds) * time.Second, true
+			}
+		}
+		// Primary rate limits are reset at a given time.
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				wait := time.Until(time.Unix(reset, 0))
+				if wait < 0 {
+					wait = 0
+				}
+				return wait + time.Second, true
+			}
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return backoff(attempt), true
+		}
+	}
+	return 0, false
+}
+
+// backoff returns an exponential delay with full jitter.
+func backoff(attempt int) time.Duration {
+	max := retryBaseDelay << uint(attempt)
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// isIdempotent returns true for requests that can safely be repeated: reads
+// against the V3 API and GraphQL queries (but not mutations). Writes are not
+// retried, since their response may be lost after they succeeded (e.g. a
+// retried delete would fail with 404).
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		if req.GetBody == nil {
+			return false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return false
+		}
+		defer body.Close()
+
+		var payload struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(body).Decode(&payload); err != nil {
+			return false
+		}
+		query := strings.TrimSpace(payload.Query)
+		return query != "" && !strings.HasPrefix(query, "mutation")
+	}
+	return false
+}
+
+// logRateLimit logs the remaining rate limit budget when it is running low.
+func logRateLimit(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil || remaining >= limit/10 {
+		return
+	}
+	log.Printf("github rate limit running low: %d of %d remaining", remaining, limit)
+}
+
+// cancelOnClose cancels the context of a request when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}